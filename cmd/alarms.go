@@ -1,14 +1,11 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
-	"text/tabwriter"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
-	"avigilon-cli/internal/client"
+	"avigilon-cli/internal/output"
+	"avigilon-cli/pkg/models"
 )
 
 // Variables to hold flag values
@@ -18,19 +15,17 @@ var (
 	alarmNote   string
 )
 
-// Helper to get authenticated client using stored config
-func getAlarmClient() *client.AvigilonClient {
-	baseUrl := viper.GetString("base_url")
-	session := viper.GetString("session_id")
-
-	if baseUrl == "" || session == "" {
-		fmt.Println("Error: Not logged in. Please run 'avigilon-cli login' first.")
-		os.Exit(1)
-	}
-
-	api := client.New(client.ClientConfig{BaseURL: baseUrl})
-	api.HTTP.SetHeader("x-avg-session", session)
-	return api
+// alarmColumns defines the table and CSV layout of 'alarms list'
+var alarmColumns = []output.Column[models.Alarm]{
+	{Header: "ID", Value: func(a models.Alarm) string { return a.ID }},
+	{Header: "NAME", Value: func(a models.Alarm) string {
+		if a.Name == "" {
+			return "[No Name]"
+		}
+		return a.Name
+	}},
+	{Header: "STATE", Value: func(a models.Alarm) string { return a.State }},
+	{Header: "TRIGGER TIME", Value: func(a models.Alarm) string { return a.TriggerTime }},
 }
 
 // Parent Command
@@ -44,50 +39,23 @@ var alarmsCmd = &cobra.Command{
 var alarmsListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List active alarms",
-	Run: func(cmd *cobra.Command, args []string) {
-		api := getAlarmClient()
-
-		alarms, err := api.GetAlarms()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		api, err := newAPIClient()
 		if err != nil {
-			fmt.Printf("Error fetching alarms: %v\n", err)
-			os.Exit(1)
+			return err
 		}
 
-		// --- JSON OUTPUT ---
-		if jsonOutput {
-			enc := json.NewEncoder(os.Stdout)
-			enc.SetIndent("", "  ")
-			if err := enc.Encode(alarms); err != nil {
-				fmt.Printf("Error encoding JSON: %v\n", err)
-				os.Exit(1)
-			}
-			return
+		alarms, err := api.GetAlarmsContext(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("fetching alarms: %w", err)
 		}
-		// -------------------
 
-		if len(alarms) == 0 {
+		if len(alarms) == 0 && outputOptions().IsTable() {
 			fmt.Println("No active alarms.")
-			return
+			return nil
 		}
 
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-		fmt.Fprintln(w, "ID\tNAME\tSTATE\tTRIGGER TIME")
-		fmt.Fprintln(w, "--\t----\t-----\t------------")
-
-		for _, a := range alarms {
-			name := a.Name
-			if name == "" {
-				name = "[No Name]"
-			}
-
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
-				a.ID,
-				name,
-				a.State,
-				a.TriggerTime,
-			)
-		}
-		w.Flush()
+		return printList(alarms, alarmColumns)
 	},
 }
 
@@ -96,19 +64,21 @@ var alarmsUpdateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Perform action on an alarm",
 	Example: `  avigilon-cli alarms update --id "zFgy_123" --action "ACKNOWLEDGE" --note "Reviewing"`,
-	Run: func(cmd *cobra.Command, args []string) {
-		session := viper.GetString("session_id")
-		api := getAlarmClient()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		api, err := newAPIClient()
+		if err != nil {
+			return err
+		}
 
 		fmt.Printf("Sending action '%s' to Alarm %s...\n", alarmAction, alarmID)
 
-		err := api.UpdateAlarm(session, alarmID, alarmAction, alarmNote)
+		err = api.UpdateAlarmContext(cmd.Context(), alarmID, alarmAction, alarmNote)
 		if err != nil {
-			fmt.Printf("Error updating alarm: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("updating alarm: %w", err)
 		}
 
 		fmt.Println("Alarm updated successfully.")
+		return nil
 	},
 }
 
@@ -0,0 +1,366 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"avigilon-cli/internal/cassette"
+	"avigilon-cli/internal/client"
+	"avigilon-cli/internal/config"
+	"avigilon-cli/internal/redact"
+	"avigilon-cli/pkg/avigilon"
+)
+
+// errNotLoggedIn is returned by the client factory when there is no session
+// to connect with.
+var errNotLoggedIn = errors.New("not logged in, please run 'avigilon-cli login' first")
+
+// newAPIClient builds the authenticated API every command talks to.
+// Commands must not call client.New themselves, so that they all behave the
+// same and a test can swap the factory for one returning the in-memory fake
+// or a client pointed at a mock server.
+//
+// Features not every API has, such as paged event searches or a view of
+// the server clock, are reached through the optional interfaces in package
+// avigilon that commands check for, e.g. avigilon.ClockAPI.
+var newAPIClient func() (avigilon.API, error) = sessionClient
+
+// loginAPI is an API that logs in with credentials of its own.
+type loginAPI interface {
+	avigilon.API
+	avigilon.ClockAPI
+	avigilon.SessionAPI
+}
+
+// newLoginClient builds the client of the commands that bring their own
+// host, TLS settings and credentials in cfg instead of the profile's
+// session: 'login', 'exporter' and 'doctor'. learned, if set, is called
+// with a certificate fingerprint trusted on first use. Like newAPIClient,
+// tests swap it.
+var newLoginClient func(cfg client.ClientConfig, learned func(fingerprint string)) (loginAPI, error) = loginClient
+
+func loginClient(cfg client.ClientConfig, learned func(fingerprint string)) (loginAPI, error) {
+	api, err := client.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	api.OnFingerprintLearned = learned
+	return api, nil
+}
+
+// clockOf returns the local clock of api, which a test may have pinned, or
+// time.Now for APIs without an avigilon.ClockAPI.
+func clockOf(api avigilon.API) func() time.Time {
+	if clock, ok := api.(avigilon.ClockAPI); ok {
+		return clock.Now
+	}
+	return time.Now
+}
+
+// clientMiddleware wraps the transport of every client the CLI builds,
+// including the ones used by 'login' and 'exporter'.
+var clientMiddleware []client.Middleware
+
+// replay answers every request from recorded cassettes when --replay is
+// given; nothing is sent to a server.
+var replay *cassette.Player
+
+// clientLogger traces the requests of every client with --verbose or
+// --debug; nil otherwise.
+var clientLogger *slog.Logger
+
+// setupMiddleware fills clientMiddleware and clientLogger from the global
+// flags before a command runs.
+func setupMiddleware() error {
+	clientMiddleware = nil
+	clientLogger = nil
+	replay = nil
+
+	level, err := traceLevel()
+	if err != nil {
+		return err
+	}
+	if level != nil {
+		clientLogger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	}
+
+	switch {
+	case recordDir != "" && replayDir != "":
+		return errors.New("--record and --replay can't be used together")
+	case recordDir != "":
+		recorder, err := cassette.NewRecorder(recordDir)
+		if err != nil {
+			return fmt.Errorf("cannot record to %s: %w", recordDir, err)
+		}
+		clientMiddleware = append(clientMiddleware, recorder.Middleware)
+	case replayDir != "":
+		player, err := cassette.Load(replayDir)
+		if err != nil {
+			return fmt.Errorf("cannot replay: %w", err)
+		}
+		replay = player
+		clientMiddleware = append(clientMiddleware, player.Middleware)
+	}
+	return nil
+}
+
+// connection holds where and how a command connects to the Web Endpoint.
+//
+// The profile (--profile, AVIGILON_PROFILE, then the current profile) picks
+// the saved settings. Each setting is then taken from its flag, its
+// environment variable, or the profile, in that order.
+type connection struct {
+	Profile string
+	BaseURL string
+	Session string
+	TLS     client.TLSConfig
+
+	// Saved is true if BaseURL and Session are the profile's own, so renewed
+	// sessions and learned fingerprints are written back to it.
+	Saved bool
+}
+
+// resolveConnection reads the connection settings of the active profile,
+// overridden by AVIGILON_HOST and AVIGILON_SESSION.
+func resolveConnection() connection {
+	savedHost := viper.GetString(config.Key("base_url"))
+	conn := connection{
+		Profile: config.ActiveProfile(),
+		BaseURL: savedHost,
+		Session: viper.GetString(config.Key("session_id")),
+		Saved:   true,
+	}
+
+	if env := strings.TrimRight(os.Getenv("AVIGILON_HOST"), "/"); env != "" && env != savedHost {
+		// A different host can't use the profile's session or certificate
+		conn.BaseURL = env
+		conn.Session = ""
+		conn.Saved = false
+	}
+	if env := os.Getenv("AVIGILON_SESSION"); env != "" {
+		conn.Session = env
+		conn.Saved = false
+	}
+	if conn.BaseURL == savedHost {
+		conn.TLS = savedTLSConfig()
+	}
+	return conn
+}
+
+// globalSetting returns the value of a global flag if it was given, else
+// the environment variable, else key from the config file, else the flag
+// default. Unlike viper.BindPFlag, this keeps flag and environment values
+// out of the config file, which is rewritten whenever the session changes.
+func globalSetting(flag, env, key string) string {
+	f := rootCmd.PersistentFlags().Lookup(flag)
+	if f.Changed {
+		return f.Value.String()
+	}
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+	if viper.IsSet(key) {
+		return viper.GetString(key)
+	}
+	return f.DefValue
+}
+
+// requestTimeout resolves --timeout, AVIGILON_TIMEOUT and "timeout".
+func requestTimeout() (time.Duration, error) {
+	raw := globalSetting("timeout", "AVIGILON_TIMEOUT", "timeout")
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", raw, err)
+	}
+	return d, nil
+}
+
+// compensateSkew resolves --compensate-skew, AVIGILON_COMPENSATE_SKEW and
+// "compensate_skew".
+func compensateSkew() (bool, error) {
+	raw := globalSetting("compensate-skew", "AVIGILON_COMPENSATE_SKEW", "compensate_skew")
+	on, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid compensate-skew setting %q: %w", raw, err)
+	}
+	return on, nil
+}
+
+// retryPolicy resolves --max-attempts, AVIGILON_MAX_ATTEMPTS and
+// "max_attempts" on top of the client's default backoff.
+func retryPolicy() (client.RetryPolicy, error) {
+	raw := globalSetting("max-attempts", "AVIGILON_MAX_ATTEMPTS", "max_attempts")
+	attempts, err := strconv.Atoi(raw)
+	if err != nil || attempts < 1 {
+		return client.RetryPolicy{}, fmt.Errorf("invalid max-attempts %q: must be a positive number", raw)
+	}
+	policy := client.DefaultRetryPolicy
+	policy.MaxAttempts = attempts
+	return policy, nil
+}
+
+// profileLimits returns the request limits of a profile: client.DefaultLimits,
+// overridden by "limits.<class>.rate", ".burst" and ".max_in_flight" where
+// class is metadata or media.
+func profileLimits(profile string) (client.Limits, error) {
+	limits := client.DefaultLimits
+	for class, limit := range map[string]*client.Limit{"metadata": &limits.Metadata, "media": &limits.Media} {
+		prefix := "limits." + class + "."
+		if key := config.ProfileKey(profile, prefix+"rate"); viper.IsSet(key) {
+			limit.Rate = viper.GetFloat64(key)
+		}
+		if key := config.ProfileKey(profile, prefix+"burst"); viper.IsSet(key) {
+			limit.Burst = viper.GetInt(key)
+		}
+		if key := config.ProfileKey(profile, prefix+"max_in_flight"); viper.IsSet(key) {
+			limit.MaxInFlight = viper.GetInt(key)
+		}
+		if limit.Rate < 0 || limit.Burst < 0 || limit.MaxInFlight < 0 {
+			return client.Limits{}, fmt.Errorf("invalid %s limits in profile %q: values must not be negative", class, profile)
+		}
+	}
+	return limits, nil
+}
+
+// traceLevel resolves --verbose (info) and --debug, AVIGILON_DEBUG or
+// "debug" (debug, adding headers). It returns nil if tracing is off.
+func traceLevel() (slog.Leveler, error) {
+	raw := globalSetting("debug", "AVIGILON_DEBUG", "debug")
+	debug, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid debug setting %q: %w", raw, err)
+	}
+	switch {
+	case debug:
+		return slog.LevelDebug, nil
+	case verbose:
+		return slog.LevelInfo, nil
+	}
+	return nil, nil
+}
+
+// newClientConfig returns the settings shared by every client: timeout,
+// retries, skew compensation, middleware and tracing from the global
+// flags, the request limits of the active profile, plus TLS.
+func newClientConfig(baseURL string, tls client.TLSConfig) (client.ClientConfig, error) {
+	timeout, err := requestTimeout()
+	if err != nil {
+		return client.ClientConfig{}, err
+	}
+	retry, err := retryPolicy()
+	if err != nil {
+		return client.ClientConfig{}, err
+	}
+	skew, err := compensateSkew()
+	if err != nil {
+		return client.ClientConfig{}, err
+	}
+	limits, err := profileLimits(config.ActiveProfile())
+	if err != nil {
+		return client.ClientConfig{}, err
+	}
+
+	return client.ClientConfig{
+		BaseURL: baseURL,
+		Timeout: timeout,
+		Retry:   retry,
+		Limits:  limits,
+		TLS:     tls,
+
+		CompensateSkew: skew,
+		Middleware:     clientMiddleware,
+		Logger:         clientLogger,
+	}, nil
+}
+
+// sessionClient is the default newAPIClient: a client for the session of the
+// active profile.
+func sessionClient() (avigilon.API, error) {
+	conn := resolveConnection()
+	if replay != nil {
+		// Replays work without a login and must not touch the profile
+		conn.Saved = false
+		if conn.BaseURL == "" {
+			conn.BaseURL = replay.Host()
+		}
+		if conn.Session == "" {
+			conn.Session = redact.Placeholder
+		}
+	}
+	if conn.BaseURL == "" || conn.Session == "" {
+		return nil, errNotLoggedIn
+	}
+
+	api, err := conn.client()
+	if err != nil {
+		return nil, err
+	}
+	if replay == nil {
+		return api, nil
+	}
+	// Let re-logins in the recording replay; the server side is recorded
+	api.Config.Username = redact.Placeholder
+	api.Config.Password = redact.Placeholder
+	api.Config.UserNonce = redact.Placeholder
+	api.Config.UserKey = redact.Placeholder
+	return api, nil
+}
+
+// client builds a client for conn. For the profile's own session, an expired
+// session is renewed transparently if credentials were saved, and the new
+// session is written back to the config file. The credential store is only
+// opened once a re-login is needed.
+func (conn connection) client() (*client.AvigilonClient, error) {
+	cfg, err := newClientConfig(conn.BaseURL, conn.TLS)
+	if err != nil {
+		return nil, err
+	}
+	api, err := client.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	api.SetSession(conn.Session)
+
+	if !conn.Saved {
+		return api, nil
+	}
+
+	if config.HasCredentials() {
+		api.LoadCredentials = func(cfg *client.ClientConfig) error {
+			creds, err := config.LoadCredentials()
+			if err != nil {
+				return err
+			}
+			cfg.Username = creds.Username
+			cfg.Password = creds.Password
+			cfg.UserNonce = creds.UserNonce
+			cfg.UserKey = creds.UserKey
+			cfg.IntegrationID = creds.IntegrationID
+			return nil
+		}
+	}
+	api.OnFingerprintLearned = pinFingerprint
+	api.OnSessionRenewed = func(sessionID string) {
+		if err := config.SaveSession(sessionID); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to save renewed session: %v\n", err)
+		}
+	}
+	// Record the last successful use for 'session show'
+	var touched sync.Once
+	api.OnSessionUsed = func() {
+		touched.Do(func() {
+			if err := config.TouchSession(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to record session use: %v\n", err)
+			}
+		})
+	}
+	return api, nil
+}
+
@@ -1,42 +1,46 @@
 package cmd
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
-	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
-	"avigilon-cli/internal/client"
+	"avigilon-cli/internal/output"
+	"avigilon-cli/pkg/models"
 )
 
 // Variables to hold flag values
 var (
 	cameraID       string
-	outputFile     string
+	snapshotFile   string
 	recordIDs      string
 	recordDuration int
 	recordStop     bool
-)
-
-// Helper to initialize client and retrieve session
-// Returns the client instance and the raw session string (needed for some payloads)
-func setupCameraClient() (*client.AvigilonClient, string) {
-	baseUrl := viper.GetString("base_url")
-	session := viper.GetString("session_id")
-
-	if baseUrl == "" || session == "" {
-		fmt.Println("Error: Not logged in. Please run 'avigilon-cli login' first.")
-		os.Exit(1)
-	}
 
-	api := client.New(client.ClientConfig{BaseURL: baseUrl})
-	// Inject session header for standard requests
-	api.HTTP.SetHeader("x-avg-session", session)
+	listCameras     cameraSelector
+	getCameras      cameraSelector
+	snapshotCameras cameraSelector
+	recordCameras   cameraSelector
+)
 
-	return api, session
+// cameraColumns defines the table and CSV layout of 'cameras list'
+var cameraColumns = []output.Column[models.Camera]{
+	{Header: "ID", Value: func(c models.Camera) string { return c.ID }},
+	{Header: "NAME", Value: func(c models.Camera) string { return c.Name }},
+	{Header: "MODEL", Value: func(c models.Camera) string { return c.Model }},
+	{Header: "STATUS", Value: func(c models.Camera) string { return c.ConnectionState }},
+	{Header: "IP", Value: func(c models.Camera) string { return c.IPAddress }},
+	{Header: "SERVER", Wide: true, Value: func(c models.Camera) string { return c.ServerID }},
+	{Header: "LOCATION", Wide: true, Value: func(c models.Camera) string { return c.Location }},
+	{Header: "MAC", Wide: true, Value: func(c models.Camera) string { return c.MACAddress }},
+	{Header: "SERIAL", Wide: true, Value: func(c models.Camera) string { return c.Serial }},
+	{Header: "FIRMWARE", Wide: true, Value: func(c models.Camera) string { return c.FirmwareVersion }},
+	{Header: "RECORDED", Wide: true, Value: func(c models.Camera) string { return strconv.FormatBool(c.RecordedData) }},
 }
 
 // Parent Command
@@ -50,66 +54,273 @@ var camerasCmd = &cobra.Command{
 var camerasListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all cameras",
-	Run: func(cmd *cobra.Command, args []string) {
-		api, _ := setupCameraClient()
+	Long: `List all cameras, or those matching the filters. Names, models, states and
+servers are matched case-insensitively, exactly or as globs; a name between
+slashes is a regular expression.`,
+	Example: `  avigilon-cli cameras list --state DISCONNECTED
+  avigilon-cli cameras list --name "Lobby*" --model "H5A-*"
+  avigilon-cli cameras list --ip 10.0.1.0/24 --server ACC-SERVER-01`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		api, err := newAPIClient()
+		if err != nil {
+			return err
+		}
 
-		cameras, err := api.GetCameras()
+		cameras, err := api.GetCamerasContext(cmd.Context())
 		if err != nil {
-			fmt.Printf("Error fetching cameras: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("fetching cameras: %w", err)
 		}
 
-		// --- JSON OUTPUT ---
-		if jsonOutput {
-			enc := json.NewEncoder(os.Stdout)
-			enc.SetIndent("", "  ")
-			if err := enc.Encode(cameras); err != nil {
-				fmt.Printf("Error encoding JSON: %v\n", err)
-				os.Exit(1)
+		if listCameras.hasFilters() {
+			cameras, err = listCameras.filter(cmd.Context(), api, cameras)
+			if err != nil {
+				return err
 			}
-			return
 		}
-		// -------------------
 
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-		fmt.Fprintln(w, "ID\tNAME\tMODEL\tSTATUS\tIP")
-		fmt.Fprintln(w, "--\t----\t-----\t------\t--")
+		return printList(cameras, cameraColumns)
+	},
+}
 
-		for _, cam := range cameras {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
-				cam.ID,
-				cam.Name,
-				cam.Model,
-				cam.ConnectionState,
-				cam.IPAddress,
-			)
+// Get Command
+var camerasGetCmd = &cobra.Command{
+	Use:   "get [id]",
+	Short: "Show all details of a camera",
+	Long: `Show everything the server reports about one camera, given by ID or by
+filters that match exactly one camera. Fields this version doesn't know
+are included in -o json and -o yaml output as received.`,
+	Example: `  avigilon-cli cameras get "camera_id_string"
+  avigilon-cli cameras get --name "Lobby East" -o json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 {
+			getCameras.IDs = []string{args[0]}
+		}
+		if getCameras.empty() {
+			return errors.New("give a camera ID or filters such as --name")
+		}
+
+		api, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+
+		// Always look the camera up, even by ID, to get all its details
+		var camera models.Camera
+		cameras, err := getCameras.lookup(cmd.Context(), api)
+		if err == nil {
+			camera, err = single(cameras)
+		}
+		if err != nil {
+			return err
+		}
+
+		opts := outputOptions()
+		if opts.IsDocument() {
+			if err := output.Encode(os.Stdout, opts.Format, camera); err != nil {
+				return fmt.Errorf("encoding output: %w", err)
+			}
+			return nil
+		}
+		if !opts.IsTable() {
+			return printList([]models.Camera{camera}, cameraColumns)
 		}
-		w.Flush()
+		printCameraDetail(camera)
+		return nil
 	},
 }
 
+// printCameraDetail prints the fields of c that are set, one per line.
+func printCameraDetail(c models.Camera) {
+	line := func(label, value string) {
+		if value != "" {
+			fmt.Printf("%-16s %s\n", label+":", value)
+		}
+	}
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+
+	line("ID", c.ID)
+	line("Name", c.Name)
+	if c.LogicalID != 0 {
+		line("Logical ID", strconv.Itoa(c.LogicalID))
+	}
+	line("Location", c.Location)
+	line("Manufacturer", c.Manufacturer)
+	line("Model", c.Model)
+	line("Serial", c.Serial)
+	line("Firmware", c.FirmwareVersion)
+	line("IP", c.IPAddress)
+	line("MAC", c.MACAddress)
+	line("Server", c.ServerID)
+	line("State", c.ConnectionState)
+	line("Recorded data", yesNo(c.RecordedData))
+	line("Resolution", formatResolution(c.Resolution))
+	line("Codec", c.Codec)
+
+	for i, st := range c.Streams {
+		label := ""
+		if i == 0 {
+			label = "Streams:"
+		}
+		var parts []string
+		for _, p := range []string{st.Name, st.Codec, formatResolution(st.Resolution)} {
+			if p != "" {
+				parts = append(parts, p)
+			}
+		}
+		if st.FrameRate > 0 {
+			parts = append(parts, fmt.Sprintf("%g fps", st.FrameRate))
+		}
+		if st.BitRate > 0 {
+			parts = append(parts, fmt.Sprintf("%d kbit/s", st.BitRate/1000))
+		}
+		fmt.Printf("%-16s %s\n", label, strings.Join(parts, ", "))
+	}
+
+	if c.PTZ != nil {
+		var axes []string
+		for name, ok := range map[string]bool{"pan": c.PTZ.Pan, "tilt": c.PTZ.Tilt, "zoom": c.PTZ.Zoom, "focus": c.PTZ.Focus} {
+			if ok {
+				axes = append(axes, name)
+			}
+		}
+		sort.Strings(axes)
+		ptz := strings.Join(axes, ", ")
+		if c.PTZ.Presets > 0 {
+			ptz += fmt.Sprintf(" (%d presets)", c.PTZ.Presets)
+		}
+		line("PTZ", ptz)
+	}
+	line("Digital inputs", formatDigitalIO(c.DigitalInputs))
+	line("Digital outputs", formatDigitalIO(c.DigitalOutputs))
+
+	if a := c.Analytics; a != nil {
+		features := append([]string(nil), a.ObjectClasses...)
+		for name, ok := range map[string]bool{"appearance search": a.Appearance, "face recognition": a.Faces, "license plates": a.LicensePlates} {
+			if ok {
+				features = append(features, name)
+			}
+		}
+		sort.Strings(features)
+		line("Analytics", strings.Join(features, ", "))
+	}
+
+	if r := c.Retention; r != nil {
+		var parts []string
+		if r.HighQualityDays > 0 {
+			parts = append(parts, fmt.Sprintf("%d days high quality", r.HighQualityDays))
+		}
+		if r.LowQualityDays > 0 {
+			parts = append(parts, fmt.Sprintf("%d days low quality", r.LowQualityDays))
+		}
+		if r.MaxDays > 0 {
+			parts = append(parts, fmt.Sprintf("at most %d days", r.MaxDays))
+		}
+		if r.OldestRecording != "" {
+			parts = append(parts, "oldest "+r.OldestRecording)
+		}
+		line("Retention", strings.Join(parts, ", "))
+	}
+
+	if len(c.Extra) > 0 {
+		keys := make([]string, 0, len(c.Extra))
+		for key := range c.Extra {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		line("Other fields", strings.Join(keys, ", ")+" (see -o json)")
+	}
+}
+
+func formatResolution(r *models.Resolution) string {
+	if r == nil || r.Width == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%dx%d", r.Width, r.Height)
+}
+
+func formatDigitalIO(ios []models.DigitalIO) string {
+	var parts []string
+	for _, io := range ios {
+		part := io.ID
+		if io.Name != "" {
+			part = fmt.Sprintf("%s (%s)", io.Name, io.ID)
+		}
+		if io.State != "" {
+			part += " " + io.State
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
+
 // Snapshot Command
 var camerasSnapshotCmd = &cobra.Command{
 	Use:   "snapshot",
 	Short: "Take a JPEG snapshot from a camera",
-	Example: `  avigilon-cli cameras snapshot --id "camera_id_string" --output "image.jpg"`,
-	Run: func(cmd *cobra.Command, args []string) {
-		api, _ := setupCameraClient()
+	Long: `Take a JPEG snapshot from the camera given by --id, or by filters that
+match exactly one camera. --at takes the frame recorded at a past time
+instead of the live view; --width, --height and --quality shape the image.
+Responses that are not a JPEG (e.g. an HTML error page) are rejected rather
+than saved.
+
+With --all or --dir, take a snapshot of every selected camera (all cameras
+with --all) concurrently and save them into --dir, named by --name-template.
+Placeholders are {name}, {id}, {timestamp}, {logicalId}, {server} and
+{location}. Live snapshots of cameras that are not CONNECTED are skipped
+unless --include-disconnected is given. A manifest with the outcome, size and
+latency of every snapshot is written to --manifest; with -o json or yaml it is
+printed too. The command fails if any snapshot failed.`,
+	Example: `  avigilon-cli cameras snapshot --id "camera_id_string" --file "image.jpg"
+  avigilon-cli cameras snapshot --name "Lobby" -f lobby.jpg
+  avigilon-cli cameras snapshot --name "Lobby" --at "yesterday 22:00" --width 640 --quality 80
+  avigilon-cli cameras snapshot --all --dir ./snapshots
+  avigilon-cli cameras snapshot --name "Parking*" --dir ./parking --name-template "{name}_{timestamp}.jpg"`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		api, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+
+		now := clockOf(api)
+		imgOpts, err := snapshotOptions(now())
+		if err != nil {
+			return err
+		}
+
+		if cameraID != "" {
+			snapshotCameras.IDs = []string{cameraID}
+		}
+		if snapshotAll || snapshotDir != "" {
+			return runBulkSnapshots(cmd.Context(), api, imgOpts, now)
+		}
+		camera, err := snapshotCameras.resolveOne(cmd.Context(), api)
+		if err != nil {
+			return err
+		}
 
-		fmt.Printf("Requesting snapshot for Camera ID: %s ...\n", cameraID)
+		if imgOpts.At.IsZero() {
+			fmt.Printf("Requesting snapshot for Camera: %s ...\n", cameraLabel(camera))
+		} else {
+			fmt.Printf("Requesting snapshot for Camera: %s at %s ...\n", cameraLabel(camera), imgOpts.At.Format(time.RFC3339))
+		}
 
-		imgData, err := api.GetSnapshot(cameraID)
+		imgData, err := api.GetSnapshotWithOptionsContext(cmd.Context(), camera.ID, imgOpts)
 		if err != nil {
-			fmt.Printf("Error getting snapshot: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("getting snapshot: %w", err)
 		}
 
-		if err := os.WriteFile(outputFile, imgData, 0644); err != nil {
-			fmt.Printf("Error writing file: %v\n", err)
-			os.Exit(1)
+		if err := os.WriteFile(snapshotFile, imgData, 0644); err != nil {
+			return fmt.Errorf("writing file: %w", err)
 		}
 
-		fmt.Printf("Snapshot saved to %s\n", outputFile)
+		fmt.Printf("Snapshot saved to %s\n", snapshotFile)
+		return nil
 	},
 }
 
@@ -117,26 +328,33 @@ var camerasSnapshotCmd = &cobra.Command{
 var camerasRecordCmd = &cobra.Command{
 	Use:   "record",
 	Short: "Trigger manual recording on cameras",
-	Long:  `Start or stop manual recording on one or more cameras.`,
+	Long: `Start or stop manual recording on one or more cameras, given by --ids or
+selected with filters.`,
 	Example: `  avigilon-cli cameras record --ids "id1,id2" --seconds 60
-  avigilon-cli cameras record --ids "id1" --stop`,
-	Run: func(cmd *cobra.Command, args []string) {
-		api, session := setupCameraClient()
+  avigilon-cli cameras record --ids "id1" --stop
+  avigilon-cli cameras record --name "Parking*" --state CONNECTED --seconds 120`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		api, err := newAPIClient()
+		if err != nil {
+			return err
+		}
 
 		// Parse IDs from comma-separated string
-		ids := strings.Split(recordIDs, ",")
-		// Clean whitespace
-		var cleanIDs []string
-		for _, id := range ids {
-			trimmed := strings.TrimSpace(id)
-			if trimmed != "" {
-				cleanIDs = append(cleanIDs, trimmed)
+		for _, id := range strings.Split(recordIDs, ",") {
+			if trimmed := strings.TrimSpace(id); trimmed != "" {
+				recordCameras.IDs = append(recordCameras.IDs, trimmed)
 			}
 		}
 
-		if len(cleanIDs) == 0 {
-			fmt.Println("Error: No valid Camera IDs provided.")
-			os.Exit(1)
+		cameras, err := recordCameras.resolve(cmd.Context(), api)
+		if err != nil {
+			return err
+		}
+
+		var ids, labels []string
+		for _, c := range cameras {
+			ids = append(ids, c.ID)
+			labels = append(labels, cameraLabel(c))
 		}
 
 		action := "START"
@@ -145,19 +363,19 @@ var camerasRecordCmd = &cobra.Command{
 		}
 
 		if action == "START" {
-			fmt.Printf("Triggering recording for %d seconds on cameras: %v\n", recordDuration, cleanIDs)
+			fmt.Printf("Triggering recording for %d seconds on cameras: %s\n", recordDuration, strings.Join(labels, ", "))
 		} else {
-			fmt.Printf("Stopping manual recording on cameras: %v\n", cleanIDs)
+			fmt.Printf("Stopping manual recording on cameras: %s\n", strings.Join(labels, ", "))
 		}
 
 		// Call Client
-		err := api.TriggerManualRecording(session, cleanIDs, action, recordDuration)
+		err = api.TriggerManualRecordingContext(cmd.Context(), ids, action, recordDuration)
 		if err != nil {
-			fmt.Printf("Error triggering recording: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("triggering recording: %w", err)
 		}
 
 		fmt.Println("Success.")
+		return nil
 	},
 }
 
@@ -167,17 +385,33 @@ func init() {
 
 	// Register Subcommands
 	camerasCmd.AddCommand(camerasListCmd)
+	camerasCmd.AddCommand(camerasGetCmd)
 	camerasCmd.AddCommand(camerasSnapshotCmd)
 	camerasCmd.AddCommand(camerasRecordCmd)
 
+	// Filters for List and Get
+	listCameras.addFilterFlags(camerasListCmd.Flags())
+	getCameras.addFilterFlags(camerasGetCmd.Flags())
+
 	// Flags for Snapshot
 	camerasSnapshotCmd.Flags().StringVar(&cameraID, "id", "", "ID of the camera")
-	camerasSnapshotCmd.Flags().StringVar(&outputFile, "output", "snapshot.jpg", "Output filename")
-	_ = camerasSnapshotCmd.MarkFlagRequired("id")
+	camerasSnapshotCmd.Flags().StringVarP(&snapshotFile, "file", "f", "snapshot.jpg", "File to save the snapshot to")
+	snapshotCameras.addFilterFlags(camerasSnapshotCmd.Flags())
+	camerasSnapshotCmd.Flags().StringVar(&snapshotAt, "at", "", "Take the frame recorded at this time instead of the live view (e.g. \"yesterday 22:00\", 2h, 2024-05-01T22:00:00Z)")
+	camerasSnapshotCmd.Flags().StringVar(&snapshotTZ, "tz", "local", "Time zone for parsing --at (e.g. UTC, America/Vancouver)")
+	camerasSnapshotCmd.Flags().IntVar(&snapshotWidth, "width", 0, "Image width in pixels (default the camera's resolution)")
+	camerasSnapshotCmd.Flags().IntVar(&snapshotHeight, "height", 0, "Image height in pixels (default the camera's resolution)")
+	camerasSnapshotCmd.Flags().IntVar(&snapshotQuality, "quality", 0, "JPEG quality from 1 to 100 (default set by the server)")
+	camerasSnapshotCmd.Flags().BoolVar(&snapshotAll, "all", false, "Take a snapshot of every camera")
+	camerasSnapshotCmd.Flags().StringVarP(&snapshotDir, "dir", "d", "", "Directory for the snapshots of several cameras (default \".\")")
+	camerasSnapshotCmd.Flags().StringVar(&snapshotTemplate, "name-template", defaultSnapshotTemplate, "File name template for the snapshots of several cameras")
+	camerasSnapshotCmd.Flags().StringVar(&snapshotManifestFile, "manifest", "", "Manifest file (default <dir>/manifest.json)")
+	camerasSnapshotCmd.Flags().IntVar(&snapshotConcurrency, "concurrency", 4, "Number of snapshots taken at once")
+	camerasSnapshotCmd.Flags().BoolVar(&snapshotDisconnected, "include-disconnected", false, "Also try cameras that are not CONNECTED")
 
 	// Flags for Record
 	camerasRecordCmd.Flags().StringVar(&recordIDs, "ids", "", "Comma separated list of Camera IDs")
 	camerasRecordCmd.Flags().IntVar(&recordDuration, "seconds", 300, "Duration in seconds (default 5 mins)")
 	camerasRecordCmd.Flags().BoolVar(&recordStop, "stop", false, "Stop recording instead of starting")
-	_ = camerasRecordCmd.MarkFlagRequired("ids")
+	recordCameras.addFilterFlags(camerasRecordCmd.Flags())
 }
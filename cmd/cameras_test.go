@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"avigilon-cli/pkg/avigilon"
+	"avigilon-cli/pkg/avigilon/fake"
+	"avigilon-cli/pkg/models"
+)
+
+// testSite returns a fake site with two servers and three cameras, one of
+// them disconnected.
+func testSite() *fake.API {
+	api := fake.New()
+	api.Servers = []models.Server{{ID: "srv-1", Name: "ACC-01"}, {ID: "srv-2", Name: "ACC-02"}}
+	api.Cameras = []models.Camera{
+		{ID: "cam-1", Name: "Lobby East", Model: "H5A-BO", ConnectionState: "CONNECTED", IPAddress: "10.0.1.10", ServerID: "srv-1"},
+		{ID: "cam-2", Name: "Lobby West", Model: "H5A-DO", ConnectionState: "CONNECTED", IPAddress: "10.0.1.11", ServerID: "srv-1"},
+		{ID: "cam-3", Name: "Parking", Model: "H4SL", ConnectionState: "DISCONNECTED", IPAddress: "10.0.2.10", ServerID: "srv-2"},
+	}
+	return api
+}
+
+func TestCamerasList(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"all", nil, []string{"cam-1", "cam-2", "cam-3"}},
+		{"by name glob", []string{"--name", "Lobby*"}, []string{"cam-1", "cam-2"}},
+		{"by state", []string{"--state", "disconnected"}, []string{"cam-3"}},
+		{"by server name", []string{"--server", "ACC-01", "--ip", "10.0.1.11"}, []string{"cam-2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := runCLI(t, testSite(), append([]string{"cameras", "list", "-o", "json"}, tt.args...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var cameras []models.Camera
+			if err := json.Unmarshal([]byte(out), &cameras); err != nil {
+				t.Fatalf("output is not a JSON list: %v\n%s", err, out)
+			}
+			var ids []string
+			for _, c := range cameras {
+				ids = append(ids, c.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got cameras %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestCamerasListTable(t *testing.T) {
+	out, err := runCLI(t, testSite(), "cameras", "list", "--columns", "NAME,STATUS", "--sort-by", "NAME")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines, want a header, a rule and 3 rows:\n%s", len(lines), out)
+	}
+	if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "NAME STATUS" {
+		t.Errorf("header = %q, want NAME and STATUS", lines[0])
+	}
+	if !strings.HasPrefix(lines[2], "Lobby East") || !strings.HasPrefix(lines[4], "Parking") {
+		t.Errorf("rows not sorted by name:\n%s", out)
+	}
+}
+
+func TestCamerasListError(t *testing.T) {
+	api := testSite()
+	api.Errors["GetCameras"] = &avigilon.APIError{StatusCode: 503}
+
+	_, err := runCLI(t, api, "cameras", "list")
+	if err == nil {
+		t.Fatal("cameras list succeeded, want an error")
+	}
+	if code := exitCodeFor(err); code != exitServerUnavailable {
+		t.Errorf("exit code %d for %v, want %d", code, err, exitServerUnavailable)
+	}
+}
+
+func TestCamerasSnapshot(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "lobby.jpg")
+	api := testSite()
+	api.Snapshots["cam-2"] = []byte("\xff\xd8\xff\xe0lobby west")
+
+	out, err := runCLI(t, api, "cameras", "snapshot", "--name", "Lobby West", "--file", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Snapshot saved to "+file) {
+		t.Errorf("unexpected output: %s", out)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, api.Snapshots["cam-2"]) {
+		t.Errorf("saved %q, want the snapshot of cam-2", data)
+	}
+}
+
+func TestCamerasSnapshotAmbiguous(t *testing.T) {
+	dir := t.TempDir()
+	_, err := runCLI(t, testSite(), "cameras", "snapshot", "--name", "Lobby*", "--file", filepath.Join(dir, "x.jpg"))
+	if err == nil {
+		t.Fatal("snapshot of two cameras to one file succeeded, want an error")
+	}
+	if _, statErr := os.Stat(filepath.Join(dir, "x.jpg")); statErr == nil {
+		t.Error("a file was written despite the error")
+	}
+}
@@ -0,0 +1,526 @@
+package cmd
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"avigilon-cli/internal/auth"
+	"avigilon-cli/internal/client"
+	"avigilon-cli/internal/config"
+	"avigilon-cli/internal/output"
+)
+
+// Outcomes of a single diagnostic check
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// certExpiryWarning is how close to expiry a server certificate gets a warning.
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// tokenFormat matches nonce:timestamp:sha256hex[:integrationId]
+var tokenFormat = regexp.MustCompile(`^[^:]+:[0-9]+:[0-9a-f]{64}(:.+)?$`)
+
+type doctorCheck struct {
+	Name       string            `json:"name"`
+	Status     string            `json:"status"`
+	Message    string            `json:"message"`
+	Details    map[string]string `json:"details,omitempty"`
+	DurationMs int64             `json:"durationMs"`
+}
+
+// doctorReport is the document printed by 'doctor -o json'.
+type doctorReport struct {
+	Profile string         `json:"profile"`
+	BaseURL string         `json:"baseUrl"`
+	Time    time.Time      `json:"time"`
+	Checks  []doctorCheck  `json:"checks"`
+	Summary map[string]int `json:"summary"`
+}
+
+// doctor runs the checks in order; later checks are skipped when the ones
+// they depend on failed.
+type doctor struct {
+	ctx     context.Context
+	timeout time.Duration
+	conn    connection
+	report  doctorReport
+
+	url     *url.URL
+	cfg     client.ClientConfig
+	api     loginAPI
+	creds   *config.Credentials
+	session bool // A working session is available for endpoint checks
+
+	// probeSession is a session doctor opened to test the saved
+	// credentials. It is ended afterwards instead of being saved, since
+	// doctor never writes the config.
+	probeSession string
+}
+
+// run times fn and records its result under name.
+func (d *doctor) run(name string, fn func(ctx context.Context) (status, message string, details map[string]string)) string {
+	ctx, cancel := context.WithTimeout(d.ctx, d.timeout)
+	defer cancel()
+
+	start := time.Now()
+	status, message, details := fn(ctx)
+	d.report.Checks = append(d.report.Checks, doctorCheck{
+		Name:       name,
+		Status:     status,
+		Message:    message,
+		Details:    details,
+		DurationMs: time.Since(start).Milliseconds(),
+	})
+	d.report.Summary[status]++
+	return status
+}
+
+func (d *doctor) skip(name, reason string) {
+	d.report.Checks = append(d.report.Checks, doctorCheck{Name: name, Status: checkSkip, Message: reason})
+	d.report.Summary[checkSkip]++
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose connectivity, TLS, clock and permission problems",
+	Long: `Runs a series of checks against the active profile and prints a pass/warn/fail
+report: DNS and TCP reachability of the host, the TLS certificate and its expiry,
+the /health response, clock skew against the server, the cached session, the
+format of the saved nonce and key, and permission to call each endpoint the CLI
+uses (cameras, alarms, events, webhooks). Doctor never changes the config: if
+the cached session expired, it tests the saved credentials with a session it
+ends afterwards.
+
+Use -o json to attach the report to a support ticket. The exit code is 1 if
+any check failed.`,
+	Example: `  avigilon-cli doctor
+  avigilon-cli doctor --profile branch -o json > doctor.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := outputOptions()
+
+		timeout, err := requestTimeout()
+		if err != nil {
+			return err
+		}
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+
+		conn := resolveConnection()
+		d := &doctor{
+			ctx:     cmd.Context(),
+			timeout: timeout,
+			conn:    conn,
+			report: doctorReport{
+				Profile: conn.Profile,
+				BaseURL: conn.BaseURL,
+				Time:    time.Now().UTC(),
+				Summary: map[string]int{},
+			},
+		}
+		d.runAll()
+
+		if opts.IsDocument() {
+			if err := output.Encode(os.Stdout, opts.Format, d.report); err != nil {
+				return fmt.Errorf("encoding output: %w", err)
+			}
+		} else {
+			printDoctorReport(d.report)
+		}
+
+		if failed := d.report.Summary[checkFail]; failed > 0 {
+			return fmt.Errorf("%d of %d checks failed", failed, len(d.report.Checks))
+		}
+
+		return nil
+	},
+}
+
+func (d *doctor) runAll() {
+	// 1. Configuration
+	if d.run("config", d.checkConfig) == checkFail {
+		for _, name := range []string{"dns", "tcp", "tls", "health", "clock", "credentials", "session", "cameras", "alarms", "events", "webhooks"} {
+			d.skip(name, "no usable configuration")
+		}
+		return
+	}
+
+	// 2. Network path
+	reachable := d.run("dns", d.checkDNS) != checkFail
+	if reachable {
+		reachable = d.run("tcp", d.checkTCP) != checkFail
+	} else {
+		d.skip("tcp", "host name does not resolve")
+	}
+	if !reachable {
+		for _, name := range []string{"tls", "health", "clock", "session", "cameras", "alarms", "events", "webhooks"} {
+			d.skip(name, "server is not reachable")
+		}
+		d.run("credentials", d.checkCredentials)
+		return
+	}
+	if d.url.Scheme == "https" {
+		d.run("tls", d.checkTLS)
+	} else {
+		d.skip("tls", "base_url does not use https")
+	}
+
+	// 3. Web Endpoint service
+	d.run("health", d.checkHealth)
+	d.run("clock", d.checkClock)
+	d.run("credentials", d.checkCredentials)
+	d.run("session", d.checkSession)
+	defer d.endProbeSession()
+
+	// 4. Endpoint permissions
+	endpoints := []struct {
+		name  string
+		check func(ctx context.Context) error
+	}{
+		{"cameras", func(ctx context.Context) error { _, err := d.api.GetCamerasContext(ctx); return err }},
+		{"alarms", func(ctx context.Context) error { _, err := d.api.GetAlarmsContext(ctx); return err }},
+		{"events", d.probeEvents},
+		{"webhooks", func(ctx context.Context) error { _, err := d.api.GetWebhooksContext(ctx); return err }},
+	}
+	for _, ep := range endpoints {
+		if !d.session {
+			d.skip(ep.name, "no valid session")
+			continue
+		}
+		d.run(ep.name, func(ctx context.Context) (string, string, map[string]string) {
+			err := ep.check(ctx)
+			switch {
+			case err == nil:
+				return checkPass, "Allowed", nil
+			case errors.Is(err, client.ErrUnauthorized):
+				return checkFail, "Permission denied: " + err.Error(), nil
+			}
+			return checkFail, err.Error(), nil
+		})
+	}
+}
+
+func (d *doctor) checkConfig(ctx context.Context) (string, string, map[string]string) {
+	details := map[string]string{"profile": d.report.Profile}
+	if used := viper.ConfigFileUsed(); used != "" {
+		details["configFile"] = used
+	}
+
+	if d.report.BaseURL == "" {
+		return checkFail, "No host configured. Run 'avigilon-cli login' first.", details
+	}
+	u, err := url.Parse(d.report.BaseURL)
+	if err != nil || u.Host == "" {
+		return checkFail, fmt.Sprintf("Invalid base_url %q", d.report.BaseURL), details
+	}
+	d.url = u
+
+	// No automatic renewal: the session check reports on it instead
+	cfg, err := newClientConfig(d.report.BaseURL, d.conn.TLS)
+	if err != nil {
+		return checkFail, err.Error(), details
+	}
+	cfg.Timeout = d.timeout
+	api, err := newLoginClient(cfg, nil)
+	if err != nil {
+		return checkFail, err.Error(), details
+	}
+	api.SetSession(d.conn.Session)
+	d.cfg = cfg
+	d.api = api
+
+	return checkPass, fmt.Sprintf("Profile '%s' uses %s", d.report.Profile, d.report.BaseURL), details
+}
+
+func (d *doctor) checkDNS(ctx context.Context) (string, string, map[string]string) {
+	host := d.url.Hostname()
+	if net.ParseIP(host) != nil {
+		return checkPass, host + " is an IP address", nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return checkFail, fmt.Sprintf("Cannot resolve %s: %v", host, err), nil
+	}
+	return checkPass, fmt.Sprintf("%s resolves to %s", host, strings.Join(addrs, ", ")), nil
+}
+
+func (d *doctor) checkTCP(ctx context.Context) (string, string, map[string]string) {
+	addr := d.address()
+	start := time.Now()
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return checkFail, fmt.Sprintf("Cannot connect to %s: %v", addr, err), nil
+	}
+	conn.Close()
+	return checkPass, fmt.Sprintf("Connected to %s in %s", addr, time.Since(start).Round(time.Millisecond)), nil
+}
+
+func (d *doctor) checkTLS(ctx context.Context) (string, string, map[string]string) {
+	tlsCfg := d.conn.TLS
+	certs, verifyErr, err := client.ProbeTLS(ctx, d.address(), tlsCfg)
+	if err != nil {
+		return checkFail, fmt.Sprintf("TLS handshake failed: %v", err), nil
+	}
+	if len(certs) == 0 {
+		return checkFail, "Server presented no certificate", nil
+	}
+
+	leaf := certs[0]
+	details := map[string]string{
+		"subject":      leaf.Subject.String(),
+		"issuer":       leaf.Issuer.String(),
+		"notBefore":    leaf.NotBefore.UTC().Format(time.RFC3339),
+		"notAfter":     leaf.NotAfter.UTC().Format(time.RFC3339),
+		"fingerprint":  client.Fingerprint(leaf.Raw),
+		"verification": tlsSummary(tlsCfg),
+	}
+	if len(leaf.DNSNames) > 0 || len(leaf.IPAddresses) > 0 {
+		details["names"] = certNames(leaf)
+	}
+	if leaf.Subject.String() == leaf.Issuer.String() {
+		details["selfSigned"] = "true"
+	}
+
+	remaining := time.Until(leaf.NotAfter)
+	switch {
+	case verifyErr != nil:
+		return checkFail, fmt.Sprintf("Certificate not trusted: %v", verifyErr), details
+	case remaining <= 0:
+		return checkFail, fmt.Sprintf("Certificate expired on %s", leaf.NotAfter.Format("2006-01-02")), details
+	case tlsCfg.Insecure:
+		return checkWarn, "Certificate verification is disabled (--insecure)", details
+	case remaining < certExpiryWarning:
+		return checkWarn, fmt.Sprintf("Certificate expires in %d days", int(remaining.Hours()/24)), details
+	}
+	return checkPass, fmt.Sprintf("Certificate trusted, expires in %d days", int(remaining.Hours()/24)), details
+}
+
+func (d *doctor) checkHealth(ctx context.Context) (string, string, map[string]string) {
+	health, err := d.api.GetHealthContext(ctx)
+	if err != nil {
+		if errors.Is(err, client.ErrUnauthorized) {
+			// Some WEP versions need a session for /health; the session check covers that
+			return checkWarn, "Health endpoint requires a valid session", nil
+		}
+		return checkFail, err.Error(), nil
+	}
+
+	details := map[string]string{"response": strings.TrimSpace(health)}
+	switch {
+	case strings.Contains(health, "GOOD"):
+		return checkPass, "Web Endpoint reports GOOD", details
+	case strings.Contains(health, "WARN"):
+		return checkWarn, "Web Endpoint reports WARN", details
+	}
+	return checkFail, "Web Endpoint reports an unhealthy state", details
+}
+
+func (d *doctor) checkClock(ctx context.Context) (string, string, map[string]string) {
+	skew, ok := d.api.ClockSkew()
+	if !ok {
+		var err error
+		if skew, err = d.api.MeasureClockSkewContext(ctx); err != nil {
+			return checkWarn, fmt.Sprintf("Cannot determine server time: %v", err), nil
+		}
+	}
+
+	details := map[string]string{
+		"skew":       skew.String(),
+		"compensate": fmt.Sprintf("%t", d.cfg.CompensateSkew),
+	}
+	abs := skew
+	if abs < 0 {
+		abs = -abs
+	}
+	switch {
+	case abs >= client.MaxClockSkew && d.cfg.CompensateSkew:
+		return checkWarn, fmt.Sprintf("Clocks differ by %s; compensated when signing", skew), details
+	case abs >= client.MaxClockSkew:
+		return checkFail, fmt.Sprintf("Clocks differ by %s; logins will be rejected (sync NTP or use --compensate-skew)", skew), details
+	case abs >= 30*time.Second:
+		return checkWarn, fmt.Sprintf("Clocks differ by %s", skew), details
+	}
+	return checkPass, fmt.Sprintf("Clocks differ by %s", skew), details
+}
+
+func (d *doctor) checkCredentials(ctx context.Context) (string, string, map[string]string) {
+	if !config.HasCredentials() {
+		return checkWarn, "No saved credentials; expired sessions need a manual login", nil
+	}
+	creds, err := config.LoadCredentials()
+	if err != nil {
+		return checkFail, fmt.Sprintf("Cannot load saved credentials: %v", err), nil
+	}
+	d.creds = &creds
+
+	details := map[string]string{
+		"username":      creds.Username,
+		"store":         viper.GetString(config.Key("credential_store")),
+		"integrationId": fmt.Sprintf("%t", creds.IntegrationID != ""),
+	}
+
+	var problems []string
+	switch {
+	case creds.UserNonce == "":
+		problems = append(problems, "nonce is empty")
+	case strings.Contains(creds.UserNonce, ":"):
+		problems = append(problems, "nonce contains ':' which breaks the token format")
+	case strings.TrimSpace(creds.UserNonce) != creds.UserNonce:
+		problems = append(problems, "nonce has leading or trailing whitespace")
+	}
+	switch {
+	case creds.UserKey == "":
+		problems = append(problems, "key is empty")
+	case strings.TrimSpace(creds.UserKey) != creds.UserKey:
+		problems = append(problems, "key has leading or trailing whitespace")
+	}
+	if creds.Password == "" {
+		problems = append(problems, "password is empty")
+	}
+	if len(problems) > 0 {
+		return checkFail, strings.Join(problems, "; "), details
+	}
+
+	// Sign as a login would: with the client's clock, on server time if
+	// skew compensation is on
+	at := d.api.Now()
+	if skew, ok := d.api.ClockSkew(); ok && d.cfg.CompensateSkew {
+		at = at.Add(skew)
+	}
+	details["signedAt"] = at.UTC().Format(time.RFC3339)
+
+	token := auth.TokenAt(creds.UserNonce, creds.UserKey, creds.IntegrationID, at)
+	if !tokenFormat.MatchString(token) {
+		return checkFail, "Generated token does not match nonce:timestamp:hash[:integrationId]", details
+	}
+	return checkPass, "Nonce and key produce a well-formed login token", details
+}
+
+func (d *doctor) checkSession(ctx context.Context) (string, string, map[string]string) {
+	if d.api.Session() != "" {
+		_, err := d.api.GetServersContext(ctx)
+		if err == nil {
+			d.session = true
+			return checkPass, "Cached session is valid", nil
+		}
+		if !errors.Is(err, client.ErrUnauthorized) {
+			return checkFail, err.Error(), nil
+		}
+	}
+
+	// Expired or missing: see whether the saved credentials still work
+	if d.creds == nil {
+		return checkFail, "No valid session and no saved credentials. Run 'avigilon-cli login'.", nil
+	}
+	cfg := d.cfg
+	cfg.Username = d.creds.Username
+	cfg.Password = d.creds.Password
+	cfg.UserNonce = d.creds.UserNonce
+	cfg.UserKey = d.creds.UserKey
+	cfg.IntegrationID = d.creds.IntegrationID
+	api, err := newLoginClient(cfg, nil)
+	if err != nil {
+		return checkFail, err.Error(), nil
+	}
+
+	sessionID, err := api.LoginContext(ctx)
+	if err != nil {
+		return checkFail, fmt.Sprintf("Cached session is invalid and login with saved credentials failed: %v", err), nil
+	}
+	d.api = api
+	d.session = true
+	d.probeSession = sessionID
+	return checkWarn, "Cached session is invalid; login with saved credentials works (run 'avigilon-cli session refresh' to renew it)", nil
+}
+
+// endProbeSession logs out of the session opened by checkSession, if any.
+func (d *doctor) endProbeSession() {
+	if d.probeSession == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(d.ctx, d.timeout)
+	defer cancel()
+	_ = d.api.LogoutSessionContext(ctx, d.probeSession)
+}
+
+// probeEvents runs the smallest possible event search on the first server.
+func (d *doctor) probeEvents(ctx context.Context) error {
+	servers, err := d.api.GetServersContext(ctx)
+	if err != nil {
+		return err
+	}
+	if len(servers) == 0 {
+		return errors.New("no servers found to search")
+	}
+
+	_, results := searchEvents(ctx, d.api, servers[:1], client.EventQuery{
+		From:     time.Now().Add(-time.Hour),
+		PageSize: 1,
+		Limit:    1,
+	}, 1)
+	return results[0].Err
+}
+
+// address returns host:port of base_url, defaulting the port by scheme.
+func (d *doctor) address() string {
+	port := d.url.Port()
+	if port == "" {
+		port = "443"
+		if d.url.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(d.url.Hostname(), port)
+}
+
+func certNames(cert *x509.Certificate) string {
+	names := append([]string(nil), cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+func printDoctorReport(r doctorReport) {
+	fmt.Printf("Profile '%s' (%s)\n\n", r.Profile, r.BaseURL)
+
+	for _, c := range r.Checks {
+		fmt.Printf("[%s]  %-12s %s\n", strings.ToUpper(c.Status), c.Name, c.Message)
+		for _, k := range sortedKeys(c.Details) {
+			fmt.Printf("               %s: %s\n", k, c.Details[k])
+		}
+	}
+
+	fmt.Printf("\nSummary: %d pass, %d warn, %d fail, %d skip\n",
+		r.Summary[checkPass], r.Summary[checkWarn], r.Summary[checkFail], r.Summary[checkSkip])
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"avigilon-cli/pkg/avigilon/fake"
+)
+
+// runDoctor runs 'doctor -o json' with args against api for a profile whose
+// host is a local listener, so the DNS and TCP checks pass, and returns the
+// report.
+func runDoctor(t *testing.T, api *fake.API, session string, args ...string) (doctorReport, error) {
+	t.Helper()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+
+	testHome(t, map[string]string{
+		".avigilon-cli.yaml":             "profiles:\n  default:\n    base_url: " + srv.URL + "\n    session_id: " + session + "\n",
+		".avigilon-cli.credentials.json": `{"username":"admin","password":"p","nonce":"n","key":"k"}`,
+	})
+	out, runErr := execCLI(t, api, append([]string{"doctor", "-o", "json"}, args...)...)
+
+	var report doctorReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out)
+	}
+	return report, runErr
+}
+
+// checkStatus returns the status of each check in r.
+func checkStatus(r doctorReport) map[string]string {
+	status := map[string]string{}
+	for _, c := range r.Checks {
+		status[c.Name] = c.Status
+	}
+	return status
+}
+
+func TestDoctor(t *testing.T) {
+	api := testSite()
+	configs := useLoginClient(t, api)
+
+	report, err := runDoctor(t, api, "s1")
+	status := checkStatus(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"config": checkPass, "dns": checkPass, "tcp": checkPass, "tls": checkSkip,
+		"health": checkPass, "clock": checkPass, "credentials": checkPass, "session": checkPass,
+		"cameras": checkPass, "alarms": checkPass, "events": checkPass, "webhooks": checkPass,
+	}
+	for name, s := range want {
+		if status[name] != s {
+			t.Errorf("%s: %q, want %q", name, status[name], s)
+		}
+	}
+	if len(*configs) != 1 || (*configs)[0].Password != "" {
+		t.Errorf("clients built with %+v, want one without credentials", *configs)
+	}
+	if api.Logins() != 0 {
+		t.Errorf("%d logins with a valid session, want none", api.Logins())
+	}
+}
+
+func TestDoctorExpiredSession(t *testing.T) {
+	api := testSite()
+	configs := useLoginClient(t, api)
+
+	// Without a cached session doctor tries the saved credentials
+	report, err := runDoctor(t, api, "")
+	status := checkStatus(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status["session"] != checkWarn || status["cameras"] != checkPass {
+		t.Errorf("session %q, cameras %q; want warn and pass", status["session"], status["cameras"])
+	}
+	if len(*configs) != 2 || (*configs)[1].Username != "admin" || (*configs)[1].UserKey != "k" {
+		t.Errorf("clients built with %+v, want a second one with the saved credentials", *configs)
+	}
+
+	// The probe session is ended, not kept
+	if api.Logins() != 1 || api.Session() != "" {
+		t.Errorf("%d logins, session %q left; want 1 login, logged out", api.Logins(), api.Session())
+	}
+}
+
+func TestDoctorClockSkew(t *testing.T) {
+	api := testSite()
+	api.Skew = 10 * time.Minute
+	useLoginClient(t, api)
+
+	report, err := runDoctor(t, api, "s1")
+	status := checkStatus(report)
+	if err == nil || !strings.Contains(err.Error(), "1 of 12 checks failed") {
+		t.Errorf("got error %v, want 1 of 12 checks failed", err)
+	}
+	if status["clock"] != checkFail {
+		t.Errorf("clock: %q, want fail", status["clock"])
+	}
+}
+
+func TestDoctorSignsWithClientClock(t *testing.T) {
+	local := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	api := testSite()
+	api.Clock = func() time.Time { return local }
+	api.Skew = 10 * time.Minute
+	useLoginClient(t, api)
+
+	// With compensation the token is signed on server time and the skew
+	// is only a warning
+	report, err := runDoctor(t, api, "s1", "--compensate-skew")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range report.Checks {
+		switch c.Name {
+		case "clock":
+			if c.Status != checkWarn {
+				t.Errorf("clock: %q, want warn", c.Status)
+			}
+		case "credentials":
+			if want := "2024-05-01T12:10:00Z"; c.Details["signedAt"] != want {
+				t.Errorf("token signed at %s, want %s", c.Details["signedAt"], want)
+			}
+		}
+	}
+}
+
+func TestDoctorInvalidTimeout(t *testing.T) {
+	testHome(t, nil)
+	t.Setenv("AVIGILON_TIMEOUT", "soon")
+	if _, err := execCLI(t, testSite(), "doctor"); err == nil || !strings.Contains(err.Error(), "invalid timeout") {
+		t.Errorf("got error %v, want the invalid timeout", err)
+	}
+}
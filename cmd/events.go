@@ -1,24 +1,39 @@
 package cmd
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"os"
 	"strings"
-	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"avigilon-cli/internal/client"
+	"avigilon-cli/internal/output"
+	"avigilon-cli/internal/timeparse"
+	"avigilon-cli/pkg/avigilon"
 	"avigilon-cli/pkg/models"
 )
 
 var (
-	eventSince  string
-	eventTopics string
+	eventSince    string
+	eventTopics   string
+	eventLimit    int
+	eventPageSize int
+	eventFrom     string
+	eventTo       string
+	eventTZ       string
+
+	eventConcurrency int
 )
 
+// eventListOutput is the JSON document printed by 'events list --json'.
+type eventListOutput struct {
+	Events  []models.Event              `json:"events"`
+	Servers []client.ServerSearchResult `json:"servers"`
+	Capped  bool                        `json:"capped"`
+}
+
 var eventsCmd = &cobra.Command{
 	Use:   "events",
 	Short: "Search historical events",
@@ -28,106 +43,217 @@ var eventsCmd = &cobra.Command{
 var eventsListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List events from history",
-	Run: func(cmd *cobra.Command, args []string) {
-		baseUrl := viper.GetString("base_url")
-		session := viper.GetString("session_id")
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := outputOptions()
 
-		if baseUrl == "" || session == "" {
-			fmt.Println("Error: Not logged in. Please run 'avigilon-cli login' first.")
-			os.Exit(1)
+		// 1. Setup Time Range (validated before touching the API)
+		loc, err := timeparse.LoadLocation(eventTZ)
+		if err != nil {
+			return err
+		}
+		from, to, err := eventTimeRange(cmd, time.Now(), loc)
+		if err != nil {
+			return err
 		}
 
-		api := client.New(client.ClientConfig{BaseURL: baseUrl})
-		api.HTTP.SetHeader("x-avg-session", session)
-
-		// 1. Get Servers
-		servers, err := api.GetServers()
+		api, err := newAPIClient()
 		if err != nil {
-			fmt.Printf("Error discovering servers: %v\n", err)
-			os.Exit(1)
+			return err
 		}
 
-		// 2. Setup Time Range
-		duration, err := time.ParseDuration(eventSince)
+		// 2. Get Servers
+		servers, err := api.GetServersContext(cmd.Context())
 		if err != nil {
-			fmt.Printf("Error parsing duration: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("discovering servers: %w", err)
 		}
-		to := time.Now().UTC()
-		from := to.Add(-duration)
 
 		// 3. Parse Topics (Clean spaces)
-		var topicsSlice []string
-		if eventTopics != "" {
-			rawSlice := strings.Split(eventTopics, ",")
-			for _, t := range rawSlice {
-				trimmed := strings.TrimSpace(t)
-				if trimmed != "" {
-					topicsSlice = append(topicsSlice, trimmed)
-				}
-			}
+		topicsSlice := parseTopics(eventTopics)
+
+		fmt.Fprintf(os.Stderr, "Searching %d servers from %s to %s (%s)...\n", len(servers),
+			from.In(loc).Format("2006-01-02 15:04"), to.In(loc).Format("2006-01-02 15:04"), loc)
+
+		// 4. Query servers in parallel and merge (--limit applies to the merged total)
+		query := client.EventQuery{
+			From:     from,
+			To:       to,
+			Topics:   topicsSlice,
+			PageSize: eventPageSize,
+			Limit:    eventLimit,
 		}
+		allEvents, results := searchEvents(cmd.Context(), api, servers, query, eventConcurrency)
 
-		fmt.Printf("Searching %d servers from %s to %s (UTC)...\n", len(servers), from.Format("15:04"), to.Format("15:04"))
+		total, failed := 0, 0
+		for _, r := range results {
+			total += r.Events
+			if r.Err != nil {
+				failed++
+			}
+		}
+		capped := eventLimit > 0 && total > len(allEvents)
+		for _, r := range results {
+			capped = capped || r.Capped
+		}
 
-		// 4. Aggregate Events
-		var allEvents []models.Event
-		for _, srv := range servers {
-			evts, err := api.GetEvents(srv.ID, from, to, topicsSlice)
-			if err != nil {
-				fmt.Printf("Warning: Failed to query server %s: %v\n", srv.Name, err)
-				continue
+		// --- JSON / YAML OUTPUT (with per-server summary) ---
+		if opts.IsDocument() {
+			out := eventListOutput{
+				Events:  allEvents,
+				Servers: results,
+				Capped:  capped,
+			}
+			if out.Events == nil {
+				out.Events = []models.Event{}
 			}
-			allEvents = append(allEvents, evts...)
+			if err := output.Encode(os.Stdout, opts.Format, out); err != nil {
+				return fmt.Errorf("encoding output: %w", err)
+			}
+			if failed == len(results) && failed > 0 {
+				return fmt.Errorf("all servers failed: %w", results[0].Err)
+			}
+			return nil
 		}
 
-		// --- JSON OUTPUT ---
-		if jsonOutput {
-			enc := json.NewEncoder(os.Stdout)
-			enc.SetIndent("", "  ")
-			if err := enc.Encode(allEvents); err != nil {
-				fmt.Printf("Error encoding JSON: %v\n", err)
-				os.Exit(1)
+		// Diagnostics go to stderr so they never mix with the results
+		for _, r := range results {
+			if r.Err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to query server %s: %v\n", r.ServerName, r.Err)
 			}
-			return
+		}
+		if capped {
+			fmt.Fprintf(os.Stderr, "Note: Results capped at %d events. Raise --limit (0 = no limit) to see more.\n", eventLimit)
+		}
+		if failed == len(results) && failed > 0 {
+			return fmt.Errorf("all servers failed: %w", results[0].Err)
 		}
 
-		if len(allEvents) == 0 {
+		if len(allEvents) == 0 && opts.IsTable() {
 			fmt.Println("No events found in this time range.")
-			return
+			return nil
 		}
 
-		// 5. Print Table
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-		fmt.Fprintln(w, "TIMESTAMP\tTYPE\tSOURCE\tSERVER")
-		fmt.Fprintln(w, "---------\t----\t------\t------")
+		// 5. Print Results
+		return printList(allEvents, eventColumns(loc))
+	},
+}
 
-		for _, e := range allEvents {
-			ts := e.Timestamp
-			// Parse ISO8601 back to local time for display
+// searchEvents runs q on every server at once if api is an
+// avigilon.EventSearchAPI.
+// Other APIs are asked for each server's events in turn, with q.Limit
+// applied per server and to the merged result just the same.
+func searchEvents(ctx context.Context, api avigilon.EventAPI, servers []models.Server, q client.EventQuery, concurrency int) ([]models.Event, []client.ServerSearchResult) {
+	if s, ok := api.(avigilon.EventSearchAPI); ok {
+		return s.SearchEventsOnServers(ctx, servers, q, concurrency)
+	}
+
+	results := make([]client.ServerSearchResult, len(servers))
+	perServer := make([][]models.Event, len(servers))
+	for i, srv := range servers {
+		events, err := api.GetEventsContext(ctx, srv.ID, q.From, q.To, q.Topics)
+		results[i] = client.ServerSearchResult{ServerID: srv.ID, ServerName: srv.Name, Err: err}
+		if err != nil {
+			results[i].Error = err.Error()
+		}
+		if q.Limit > 0 && len(events) > q.Limit {
+			events = events[:q.Limit]
+			results[i].Capped = true
+		}
+		results[i].Events = len(events)
+		perServer[i] = events
+	}
+	return client.MergeEvents(perServer, q.Limit), results
+}
+
+// eventColumns defines the table and CSV layout of event listings,
+// with timestamps shown in loc. The widths apply to 'events follow'.
+func eventColumns(loc *time.Location) []output.Column[models.Event] {
+	return []output.Column[models.Event]{
+		{Header: "TIMESTAMP", Width: 19, Value: func(e models.Event) string {
+			// Parse ISO8601 back to the --tz zone for display
 			if t, err := time.Parse(time.RFC3339, e.Timestamp); err == nil {
-				// UPDATED: Format now includes Date + Time (YYYY-MM-DD HH:MM:SS)
-				ts = t.Local().Format("2006-01-02 15:04:05")
+				return t.In(loc).Format("2006-01-02 15:04:05")
 			}
+			return e.Timestamp
+		}},
+		{Header: "TYPE", Width: 30, Value: func(e models.Event) string { return e.Type }},
+		{Header: "SOURCE", Width: 24, Value: eventSource},
+		{Header: "SERVER", Width: 12, Value: func(e models.Event) string { return e.Server }},
+		{Header: "ID", Wide: true, Width: 36, Value: func(e models.Event) string { return e.ID }},
+	}
+}
 
-			source := e.CameraID
-			if source == "" {
-				source = e.UserName
-			}
-			if source == "" {
-				source = "System"
-			}
+// eventSource names what raised an event: a camera, a user or the system.
+func eventSource(e models.Event) string {
+	if e.CameraID != "" {
+		return e.CameraID
+	}
+	if e.UserName != "" {
+		return e.UserName
+	}
+	return "System"
+}
 
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", ts, e.Type, source, e.Server)
+// parseTopics splits a comma separated --topics value, dropping blanks.
+func parseTopics(raw string) []string {
+	var topics []string
+	for _, t := range strings.Split(raw, ",") {
+		trimmed := strings.TrimSpace(t)
+		if trimmed != "" {
+			topics = append(topics, trimmed)
 		}
-		w.Flush()
-	},
+	}
+	return topics
+}
+
+// eventTimeRange resolves --from/--to, falling back to --since when --from
+// is not given, and checks that the range is not empty.
+func eventTimeRange(cmd *cobra.Command, now time.Time, loc *time.Location) (time.Time, time.Time, error) {
+	if cmd.Flags().Changed("since") && cmd.Flags().Changed("from") {
+		return time.Time{}, time.Time{}, fmt.Errorf("--since and --from are mutually exclusive")
+	}
+
+	to := now
+	if eventTo != "" {
+		t, err := timeparse.Parse(eventTo, now, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("--to: %w", err)
+		}
+		to = t
+	}
+
+	var from time.Time
+	if eventFrom != "" {
+		t, err := timeparse.Parse(eventFrom, now, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("--from: %w", err)
+		}
+		from = t
+	} else {
+		duration, err := timeparse.ParseDuration(eventSince)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("--since: %w", err)
+		}
+		from = to.Add(-duration)
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start of range (%s) must be before its end (%s)",
+			from.In(loc).Format(time.RFC3339), to.In(loc).Format(time.RFC3339))
+	}
+
+	return from, to, nil
 }
 
 func init() {
 	rootCmd.AddCommand(eventsCmd)
 	eventsCmd.AddCommand(eventsListCmd)
 
-	eventsListCmd.Flags().StringVar(&eventSince, "since", "1h", "Look back duration (e.g. 30m, 1h, 24h)")
+	eventsListCmd.Flags().StringVar(&eventSince, "since", "1h", "Look back duration (e.g. 30m, 1h, 24h, 7d)")
+	eventsListCmd.Flags().StringVar(&eventFrom, "from", "", "Start of range: RFC3339, \"2006-01-02 15:04\", \"yesterday 22:00\" or \"2h ago\"")
+	eventsListCmd.Flags().StringVar(&eventTo, "to", "", "End of range, same formats as --from (default now)")
+	eventsListCmd.Flags().StringVar(&eventTZ, "tz", "local", "Time zone for parsing --from/--to and displaying timestamps (e.g. UTC, America/Vancouver)")
 	eventsListCmd.Flags().StringVar(&eventTopics, "topics", "", "Comma separated list of event topics")
+	eventsListCmd.Flags().IntVar(&eventLimit, "limit", 0, "Maximum number of events to return across all servers (0 = no limit)")
+	eventsListCmd.Flags().IntVar(&eventConcurrency, "concurrency", 4, "Number of servers queried in parallel")
+	eventsListCmd.Flags().IntVar(&eventPageSize, "page-size", client.DefaultEventPageSize, "Number of events fetched per request")
 }
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"avigilon-cli/internal/client"
+	"avigilon-cli/internal/output"
+	"avigilon-cli/internal/timeparse"
+	"avigilon-cli/pkg/avigilon"
+	"avigilon-cli/pkg/models"
+)
+
+var (
+	followInterval time.Duration
+	followBacklog  string
+	followTopics   string
+	followTZ       string
+)
+
+// followState tracks what has already been printed for one server.
+type followState struct {
+	server    models.Server
+	highWater time.Time            // Timestamp of the newest event seen
+	seen      map[string]time.Time // Event key -> timestamp, for events at or near highWater
+}
+
+var eventsFollowCmd = &cobra.Command{
+	Use:   "follow",
+	Short: "Stream new events as they happen (like tail -f)",
+	Long: `Polls every server in the cluster for new events and prints them as they
+arrive, until interrupted with Ctrl+C. With --output json (or ndjson) each event
+is written as one JSON object per line; csv, template and jsonpath output are
+streamed the same way. Tables have fixed column widths, chosen with --columns;
+events are printed as they arrive, so --sort-by is not supported.
+
+Expired sessions are renewed automatically when credentials were saved at login,
+and failed polls are reported on stderr and retried on the next interval.`,
+	Example: `  avigilon-cli events follow
+  avigilon-cli events follow --topics "DEVICE_MOTION_START" -o ndjson
+  avigilon-cli events follow --backlog 15m --interval 10s`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if followInterval <= 0 {
+			return errors.New("--interval must be positive")
+		}
+
+		loc, err := timeparse.LoadLocation(followTZ)
+		if err != nil {
+			return err
+		}
+
+		// A stream has no single document, so JSON is emitted as NDJSON
+		opts := outputOptions()
+		if opts.Format == output.FormatJSON {
+			opts.Format = output.FormatNDJSON
+		}
+		stream, err := output.NewStream(os.Stdout, opts, eventColumns(loc))
+		if err != nil {
+			return err
+		}
+
+		backlog, err := timeparse.ParseDuration(followBacklog)
+		if err != nil {
+			return fmt.Errorf("--backlog: %w", err)
+		}
+
+		api, err := newAPIClient()
+		if err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		// 1. Discover Servers
+		servers, err := api.GetServersContext(ctx)
+		if err != nil {
+			return fmt.Errorf("discovering servers: %w", err)
+		}
+
+		// The high-water mark is compared with server timestamps
+		start := serverNow(ctx, api).Add(-backlog)
+		states := make([]*followState, 0, len(servers))
+		for _, srv := range servers {
+			states = append(states, &followState{
+				server:    srv,
+				highWater: start,
+				seen:      make(map[string]time.Time),
+			})
+		}
+
+		topics := parseTopics(followTopics)
+		fmt.Fprintf(os.Stderr, "Following events on %d servers (Ctrl+C to stop)...\n", len(servers))
+
+		if err := stream.Print(nil); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+
+		// 2. Poll until interrupted
+		ticker := time.NewTicker(followInterval)
+		defer ticker.Stop()
+
+		for {
+			for _, st := range states {
+				events, err := st.poll(ctx, api, topics)
+				if err != nil {
+					if ctx.Err() != nil {
+						return nil
+					}
+					// Without saved credentials an expired session can't recover
+					if errors.Is(err, client.ErrUnauthorized) && !canRelogin(api) {
+						return fmt.Errorf("%w\nSession expired. Please run 'avigilon-cli login' again", err)
+					}
+					fmt.Fprintf(os.Stderr, "Warning: Failed to poll server %s: %v\n", st.server.Name, err)
+					continue
+				}
+
+				if err := stream.Print(events); err != nil {
+					return fmt.Errorf("writing output: %w", err)
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	},
+}
+
+// serverNow estimates the server's current time from the clock skew seen in
+// its Date headers, measuring it if no response carried one yet. APIs
+// without an avigilon.ClockAPI share the local clock.
+func serverNow(ctx context.Context, api avigilon.API) time.Time {
+	clock, ok := api.(avigilon.ClockAPI)
+	if !ok {
+		return time.Now()
+	}
+	skew, ok := clock.ClockSkew()
+	if !ok {
+		skew, _ = clock.MeasureClockSkewContext(ctx)
+	}
+	return clock.Now().Add(skew)
+}
+
+// canRelogin reports whether api renews expired sessions itself.
+func canRelogin(api avigilon.API) bool {
+	s, ok := api.(avigilon.SessionAPI)
+	return ok && s.CanRelogin()
+}
+
+// poll fetches events from the high-water mark onwards and returns the ones
+// not printed before, oldest first. The search start is inclusive, so events
+// sharing the high-water timestamp come back on every poll; the seen set
+// filters them out.
+func (st *followState) poll(ctx context.Context, api avigilon.EventAPI, topics []string) ([]models.Event, error) {
+	events, err := api.GetEventsContext(ctx, st.server.ID, st.highWater, time.Time{}, topics)
+	if err != nil {
+		return nil, err
+	}
+
+	type stamped struct {
+		event models.Event
+		at    time.Time
+	}
+	var fresh []stamped
+	for _, e := range events {
+		key := eventKey(e)
+		if _, ok := st.seen[key]; ok {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, e.Timestamp)
+		if err != nil {
+			at = st.highWater
+		}
+		st.seen[key] = at
+		fresh = append(fresh, stamped{event: e, at: at})
+	}
+
+	sort.SliceStable(fresh, func(i, j int) bool { return fresh[i].at.Before(fresh[j].at) })
+
+	out := make([]models.Event, 0, len(fresh))
+	for _, f := range fresh {
+		if f.at.After(st.highWater) {
+			st.highWater = f.at
+		}
+		out = append(out, f.event)
+	}
+
+	// Only events at the high-water mark can be returned again
+	for key, at := range st.seen {
+		if at.Before(st.highWater) {
+			delete(st.seen, key)
+		}
+	}
+
+	return out, nil
+}
+
+// eventKey identifies an event for de-duplication. Events without an ID
+// fall back to their content.
+func eventKey(e models.Event) string {
+	if e.ID != "" {
+		return e.ID
+	}
+	return e.Timestamp + "|" + e.Type + "|" + e.Server + "|" + eventSource(e)
+}
+
+func init() {
+	eventsCmd.AddCommand(eventsFollowCmd)
+
+	eventsFollowCmd.Flags().DurationVar(&followInterval, "interval", 5*time.Second, "How often to poll the servers")
+	eventsFollowCmd.Flags().StringVar(&followBacklog, "backlog", "0s", "Also print events from this far back on start (e.g. 15m)")
+	eventsFollowCmd.Flags().StringVar(&followTopics, "topics", "", "Comma separated list of event topics")
+	eventsFollowCmd.Flags().StringVar(&followTZ, "tz", "local", "Time zone for displaying timestamps")
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"avigilon-cli/pkg/models"
+)
+
+func TestFollowPoll(t *testing.T) {
+	ctx := context.Background()
+	local := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	api := testSite()
+	api.Clock = func() time.Time { return local }
+	api.Skew = 2 * time.Minute
+
+	// The high-water mark starts at the server's time
+	start := serverNow(ctx, api)
+	if !start.Equal(local.Add(2 * time.Minute)) {
+		t.Fatalf("serverNow() = %s, want the fake clock plus its skew", start)
+	}
+	at := func(d time.Duration) string { return start.Add(d).Format(time.RFC3339) }
+	add := func(id string, d time.Duration) {
+		api.AddEvent("srv-1", models.Event{ID: id, Type: "DEVICE_MOTION_START", Timestamp: at(d)})
+	}
+	st := &followState{server: api.Servers[0], highWater: start, seen: map[string]time.Time{}}
+
+	polls := []struct {
+		name string
+		add  func()
+		want string
+		mark time.Duration
+	}{
+		{"before the start", func() { add("old", -time.Second) }, "", 0},
+		{"new events, two at the same time", func() { add("e2", 10*time.Second); add("e3", 10*time.Second); add("e1", 5*time.Second) }, "e1,e2,e3", 10 * time.Second},
+		{"same page again", func() {}, "", 10 * time.Second},
+		{"late event at the high-water mark", func() { add("e4", 10*time.Second); add("e5", 20*time.Second) }, "e4,e5", 20 * time.Second},
+		{"overlap only", func() {}, "", 20 * time.Second},
+	}
+	for _, p := range polls {
+		p.add()
+		events, err := st.poll(ctx, api, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, e := range events {
+			ids = append(ids, e.ID)
+		}
+		if got := strings.Join(ids, ","); got != p.want {
+			t.Errorf("%s: got %q, want %q", p.name, got, p.want)
+		}
+		if !st.highWater.Equal(start.Add(p.mark)) {
+			t.Errorf("%s: high-water mark %s, want %s", p.name, st.highWater, start.Add(p.mark))
+		}
+	}
+
+	// Only the events at the high-water mark are remembered
+	if len(st.seen) != 1 {
+		t.Errorf("%d events remembered, want 1: %v", len(st.seen), st.seen)
+	}
+}
+
+// follow runs 'events follow' with args over eventSite until a few polls
+// are done and returns the output.
+func follow(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	testHome(t, nil)
+	args = append([]string{"events", "follow", "--backlog", "1h", "--tz", "UTC", "--interval", "10ms"}, args...)
+	return execCLIContext(ctx, t, eventSite(), args...)
+}
+
+func TestFollowColumns(t *testing.T) {
+	out, err := follow(t, "--columns", "TYPE,SERVER")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		fmt.Sprintf("%-30s   %s", "TYPE", "SERVER"),
+		fmt.Sprintf("%-30s   %s", "DEVICE_MOTION_START", "ACC-01"),
+		fmt.Sprintf("%-30s   %s", "DEVICE_MOTION_STOP", "ACC-01"),
+		fmt.Sprintf("%-30s   %s", "USER_LOGIN", "ACC-02"),
+		fmt.Sprintf("%-30s   %s", "DEVICE_DISCONNECTED", "ACC-02"),
+	}
+	if got := strings.TrimSpace(out); got != strings.Join(want, "\n") {
+		t.Errorf("output:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestFollowCSV(t *testing.T) {
+	out, err := follow(t, "-o", "csv", "--columns", "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Fields(out); strings.Join(got, ",") != "ID,e1,e3,e2,e4" {
+		t.Errorf("output:\n%s\nwant one header and each event once", out)
+	}
+}
+
+func TestFollowRejects(t *testing.T) {
+	for _, args := range [][]string{
+		{"--sort-by", "TYPE"},
+		{"--columns", "NOPE"},
+		{"-o", "yaml"},
+	} {
+		if out, err := follow(t, args...); err == nil {
+			t.Errorf("follow %v succeeded, want an error:\n%s", args, out)
+		}
+	}
+}
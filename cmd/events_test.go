@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"avigilon-cli/pkg/avigilon"
+	"avigilon-cli/pkg/avigilon/fake"
+	"avigilon-cli/pkg/avigilon/mockserver"
+	"avigilon-cli/pkg/models"
+)
+
+// eventSite returns testSite with events on both servers, interleaved in
+// time, and one event outside the default one hour range.
+func eventSite() *fake.API {
+	api := testSite()
+	at := func(ago time.Duration) string { return time.Now().Add(-ago).UTC().Format(time.RFC3339) }
+	api.Events["srv-1"] = []models.Event{
+		{ID: "e1", Type: "DEVICE_MOTION_START", Timestamp: at(50 * time.Minute), Server: "ACC-01", CameraID: "cam-1"},
+		{ID: "e3", Type: "DEVICE_MOTION_STOP", Timestamp: at(30 * time.Minute), Server: "ACC-01", CameraID: "cam-1"},
+		{ID: "old", Type: "DEVICE_MOTION_START", Timestamp: at(3 * time.Hour), Server: "ACC-01", CameraID: "cam-2"},
+	}
+	api.Events["srv-2"] = []models.Event{
+		{ID: "e2", Type: "USER_LOGIN", Timestamp: at(40 * time.Minute), Server: "ACC-02", UserName: "admin"},
+		{ID: "e4", Type: "DEVICE_DISCONNECTED", Timestamp: at(10 * time.Minute), Server: "ACC-02", CameraID: "cam-3"},
+	}
+	return api
+}
+
+func TestEventsList(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		want   []string
+		capped bool
+	}{
+		{"merged by time", nil, []string{"e1", "e2", "e3", "e4"}, false},
+		{"topics", []string{"--topics", "DEVICE_MOTION"}, []string{"e1", "e3"}, false},
+		{"since", []string{"--since", "4h"}, []string{"old", "e1", "e2", "e3", "e4"}, false},
+		{"limit", []string{"--limit", "3"}, []string{"e1", "e2", "e3"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := runCLI(t, eventSite(), append([]string{"events", "list", "-o", "json"}, tt.args...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got eventListOutput
+			if err := json.Unmarshal([]byte(out), &got); err != nil {
+				t.Fatalf("output is not JSON: %v\n%s", err, out)
+			}
+			var ids []string
+			for _, e := range got.Events {
+				ids = append(ids, e.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got events %v, want %v", ids, tt.want)
+			}
+			if got.Capped != tt.capped {
+				t.Errorf("capped = %v, want %v", got.Capped, tt.capped)
+			}
+			if len(got.Servers) != 2 {
+				t.Errorf("got %d server summaries, want 2", len(got.Servers))
+			}
+		})
+	}
+}
+
+func TestEventsListAllServersFail(t *testing.T) {
+	api := eventSite()
+	api.Errors["GetEvents"] = &avigilon.APIError{StatusCode: 403, Message: "no permission"}
+
+	out, err := runCLI(t, api, "events", "list", "-o", "json")
+	if err == nil {
+		t.Fatal("events list succeeded, want an error")
+	}
+	if code := exitCodeFor(err); code != exitUnauthorized {
+		t.Errorf("exit code %d for %v, want %d", code, err, exitUnauthorized)
+	}
+
+	// The per-server summary is still printed
+	var got eventListOutput
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out)
+	}
+	for _, s := range got.Servers {
+		if !strings.Contains(s.Error, "no permission") {
+			t.Errorf("server %s: error %q, want the cause", s.ServerID, s.Error)
+		}
+	}
+}
+
+func TestEventsListTable(t *testing.T) {
+	out, err := runCLI(t, eventSite(), "events", "list", "--tz", "UTC", "--columns", "TYPE")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"TYPE", "----", "DEVICE_MOTION_START", "USER_LOGIN", "DEVICE_MOTION_STOP", "DEVICE_DISCONNECTED"}
+	if got := strings.Fields(out); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("table:\n%s\nwant the types in time order", out)
+	}
+}
+
+func TestEventsListOverHTTP(t *testing.T) {
+	// The HTTP client searches with its own paging; the result is the same
+	srv := mockserver.New(eventSite(), mockserver.Options{
+		Credentials: mockserver.Credentials{Username: "admin", Password: "p", UserNonce: "n", UserKey: "k"},
+	})
+	defer srv.Close()
+	api, err := avigilon.New(srv.ClientConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runCLI(t, api, "events", "list", "-o", "json", "--page-size", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got eventListOutput
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out)
+	}
+	var ids []string
+	for _, e := range got.Events {
+		ids = append(ids, e.ID)
+	}
+	if strings.Join(ids, ",") != "e1,e2,e3,e4" {
+		t.Errorf("got events %v, want e1,e2,e3,e4", ids)
+	}
+	if srv.Logins() != 1 {
+		t.Errorf("%d logins, want 1", srv.Logins())
+	}
+}
@@ -2,10 +2,12 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -14,8 +16,10 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"avigilon-cli/internal/client"
-	"avigilon-cli/pkg/models"
+	"avigilon-cli/internal/config"
+	"avigilon-cli/pkg/avigilon"
 )
 
 // Variables to hold flag values
@@ -28,15 +32,17 @@ var (
 	expIntID      string
 	expPort       string
 	serviceAction string // "install", "uninstall", "start", "stop"
+	expTLS        tlsFlags
 )
 
 // --- SERVICE WRAPPER ---
 
 // program implements the kardianos/service interface
 type program struct {
-	exit    chan struct{}
-	server  *http.Server
-	api     *client.AvigilonClient
+	exit     chan struct{}
+	server   *http.Server
+	api      loginAPI
+	scrapeMu sync.Mutex // Serializes scrapes against the VMS
 }
 
 func (p *program) Start(s service.Service) error {
@@ -49,7 +55,7 @@ func (p *program) Start(s service.Service) error {
 func (p *program) run() {
 	// 1. Initial Login
 	log.Println("Attempting initial login...")
-	if _, err := p.api.Login(); err != nil {
+	if _, err := p.api.LoginContext(context.Background()); err != nil {
 		log.Printf("Fatal: Initial login failed: %v", err)
 		// We exit here so the service manager (systemd/Windows Services) knows we failed and can handle restarts.
 		os.Exit(1)
@@ -57,16 +63,8 @@ func (p *program) run() {
 	log.Println("Initial login successful.")
 
 	// 2. Setup Prometheus
-	registry := prometheus.NewRegistry()
-	collector := &AvigilonCollector{Client: p.api}
-	registry.MustRegister(collector)
-
-	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{
-		ErrorLog: log.Default(),
-	})
-
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", handler)
+	mux.Handle("/metrics", p.metricsHandler())
 
 	addr := fmt.Sprintf(":%s", expPort)
 	p.server = &http.Server{
@@ -82,6 +80,42 @@ func (p *program) run() {
 	}
 }
 
+// metricsHandler runs every scrape with its own collector bound to a deadline
+// taken from Prometheus' scrape timeout, so a hung VMS fails the scrape
+// instead of holding the scrape lock indefinitely.
+func (p *program) metricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := scrapeContext(r)
+		defer cancel()
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(&AvigilonCollector{
+			Client:  p.api,
+			Context: ctx,
+			Mutex:   &p.scrapeMu,
+		})
+
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+			ErrorLog: log.Default(),
+		}).ServeHTTP(w, r)
+	})
+}
+
+// scrapeContext derives the scrape deadline from the
+// X-Prometheus-Scrape-Timeout-Seconds header sent by Prometheus.
+func scrapeContext(r *http.Request) (context.Context, context.CancelFunc) {
+	if v := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); v != "" {
+		if secs, err := strconv.ParseFloat(v, 64); err == nil {
+			// Leave headroom to write the response before Prometheus gives up
+			timeout := time.Duration(secs*float64(time.Second)) - scrapeTimeoutOffset
+			if timeout > 0 {
+				return context.WithTimeout(r.Context(), timeout)
+			}
+		}
+	}
+	return context.WithCancel(r.Context())
+}
+
 func (p *program) Stop(s service.Service) error {
 	// Stop should not block. Signal the app to stop.
 	log.Println("Stopping service...")
@@ -93,6 +127,15 @@ func (p *program) Stop(s service.Service) error {
 			log.Printf("Server forced to shutdown: %v", err)
 		}
 	}
+
+	// End the session so restarts don't pile up sessions on the VMS
+	if session := p.api.Session(); session != "" {
+		if err := p.api.LogoutSessionContext(ctx, session); err != nil {
+			log.Printf("Logout failed: %v", err)
+		} else {
+			log.Println("Logged out.")
+		}
+	}
 	close(p.exit)
 	return nil
 }
@@ -100,10 +143,14 @@ func (p *program) Stop(s service.Service) error {
 // --- COLLECTOR LOGIC ---
 
 type AvigilonCollector struct {
-	Client *client.AvigilonClient
-	Mutex  sync.Mutex
+	Client  avigilon.API
+	Context context.Context // Bounds the API calls of a single scrape
+	Mutex   *sync.Mutex
 }
 
+// scrapeTimeoutOffset is subtracted from Prometheus' scrape timeout.
+const scrapeTimeoutOffset = 500 * time.Millisecond
+
 var (
 	upDesc = prometheus.NewDesc(
 		"avigilon_up", "Was the last scrape successful.", nil, nil,
@@ -111,6 +158,9 @@ var (
 	scrapeDurationDesc = prometheus.NewDesc(
 		"avigilon_scrape_duration_seconds", "Time taken to scrape API.", nil, nil,
 	)
+	clockSkewDesc = prometheus.NewDesc(
+		"avigilon_clock_skew_seconds", "Server clock minus local clock, from the server's Date header.", nil, nil,
+	)
 	systemHealthDesc = prometheus.NewDesc(
 		"avigilon_system_health", "VMS Health Status (1.0=GOOD, 0.5=WARN, 0.0=BAD).", nil, nil,
 	)
@@ -134,6 +184,7 @@ var (
 func (c *AvigilonCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- upDesc
 	ch <- scrapeDurationDesc
+	ch <- clockSkewDesc
 	ch <- systemHealthDesc
 	ch <- serverCountDesc
 	ch <- cameraUpDesc
@@ -149,7 +200,7 @@ func (c *AvigilonCollector) Collect(ch chan<- prometheus.Metric) {
 	success := 1.0
 
 	// 1. Health
-	healthStr, err := c.Client.GetHealth()
+	healthStr, err := c.Client.GetHealthContext(c.Context)
 	healthVal := 0.0
 	if err == nil {
 		if strings.Contains(healthStr, "GOOD") {
@@ -162,12 +213,12 @@ func (c *AvigilonCollector) Collect(ch chan<- prometheus.Metric) {
 	ch <- prometheus.MustNewConstMetric(systemHealthDesc, prometheus.GaugeValue, healthVal)
 
 	// 2. Servers
-	if srvs, err := c.fetchServersWithRetry(); err == nil {
+	if srvs, err := c.Client.GetServersContext(c.Context); err == nil {
 		ch <- prometheus.MustNewConstMetric(serverCountDesc, prometheus.GaugeValue, float64(len(srvs)))
 	}
 
 	// 3. Cameras
-	if cams, err := c.fetchCamerasWithRetry(); err == nil {
+	if cams, err := c.Client.GetCamerasContext(c.Context); err == nil {
 		stateCounts := make(map[string]float64)
 		for _, cam := range cams {
 			isUp := 0.0
@@ -202,7 +253,7 @@ func (c *AvigilonCollector) Collect(ch chan<- prometheus.Metric) {
 	}
 
 	// 4. Alarms
-	if alarms, err := c.fetchAlarmsWithRetry(); err == nil {
+	if alarms, err := c.Client.GetAlarmsContext(c.Context); err == nil {
 		alarmStates := make(map[string]float64)
 		for _, a := range alarms {
 			st := strings.ToUpper(a.State)
@@ -219,53 +270,57 @@ func (c *AvigilonCollector) Collect(ch chan<- prometheus.Metric) {
 		log.Printf("Error scraping alarms: %v", err)
 	}
 
+	// 5. Clock skew, as seen in the responses of this scrape (HTTP clients only)
+	if clock, ok := c.Client.(avigilon.ClockAPI); ok {
+		if skew, ok := clock.ClockSkew(); ok {
+			ch <- prometheus.MustNewConstMetric(clockSkewDesc, prometheus.GaugeValue, skew.Seconds())
+		}
+	}
+
 	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, success)
 	ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, time.Since(start).Seconds())
 }
 
-// --- RETRY HELPERS ---
-func (c *AvigilonCollector) fetchCamerasWithRetry() ([]models.Camera, error) {
-	res, err := c.Client.GetCameras()
-	if err == nil {
-		return res, nil
+// --- COMMAND ---
+
+// applyProfileFallbacks fills exporter settings still missing after flags and
+// environment from the active profile, its TLS settings and its saved
+// credentials. The TLS settings and credentials only apply to the profile's
+// own host.
+func applyProfileFallbacks(cmd *cobra.Command) {
+	if expHost == "" {
+		expHost = viper.GetString(config.Key("base_url"))
 	}
-	if isAuthError(err) {
-		if _, e := c.Client.Login(); e == nil {
-			return c.Client.GetCameras()
+	expTLS.applyProfile(expHost)
+	if !cmd.Flags().Changed("username") && os.Getenv("AVIGILON_USERNAME") == "" {
+		if saved := viper.GetString(config.Key("username")); saved != "" {
+			expUser = saved
 		}
 	}
-	return nil, err
-}
-func (c *AvigilonCollector) fetchAlarmsWithRetry() ([]models.Alarm, error) {
-	res, err := c.Client.GetAlarms()
-	if err == nil {
-		return res, nil
+
+	// Saved credentials belong to the profile's host only
+	if expPass != "" && expNonce != "" && expKey != "" ||
+		strings.TrimRight(expHost, "/") != viper.GetString(config.Key("base_url")) {
+		return
 	}
-	if isAuthError(err) {
-		if _, e := c.Client.Login(); e == nil {
-			return c.Client.GetAlarms()
-		}
+	creds, err := config.LoadCredentials()
+	if err != nil {
+		return
 	}
-	return nil, err
-}
-func (c *AvigilonCollector) fetchServersWithRetry() ([]models.Server, error) {
-	res, err := c.Client.GetServers()
-	if err == nil {
-		return res, nil
+	if expPass == "" {
+		expPass = creds.Password
 	}
-	if isAuthError(err) {
-		if _, e := c.Client.Login(); e == nil {
-			return c.Client.GetServers()
-		}
+	if expNonce == "" {
+		expNonce = creds.UserNonce
+	}
+	if expKey == "" {
+		expKey = creds.UserKey
+	}
+	if expIntID == "" {
+		expIntID = creds.IntegrationID
 	}
-	return nil, err
-}
-func isAuthError(err error) bool {
-	return strings.Contains(err.Error(), "401") || strings.Contains(err.Error(), "403")
 }
 
-// --- COMMAND ---
-
 var exporterCmd = &cobra.Command{
 	Use:   "exporter",
 	Short: "Start Prometheus Exporter service",
@@ -284,8 +339,18 @@ This is the recommended way to configure the Windows Service via Registry
   AVIGILON_KEY
   AVIGILON_INTEGRATION_ID
   AVIGILON_PORT
+  AVIGILON_TLS_CA, AVIGILON_TLS_CERT, AVIGILON_TLS_KEY
+  AVIGILON_TLS_FINGERPRINT
+  AVIGILON_INSECURE, AVIGILON_TOFU (set to "true")
+  AVIGILON_PROFILE
+  AVIGILON_COMPENSATE_SKEW (set to "true")
+  AVIGILON_CREDENTIALS_PASSPHRASE (for credentials saved with --credential-store encrypted)
+
+Anything not given by flag or environment is read from the selected profile
+(--profile or AVIGILON_PROFILE): host, username and TLS options from the config
+file, and password, nonce and key from the credentials saved by 'login'.
 `,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 
 		// ---------------------------------------------------------
 		// 1. Runtime Fallback: Check Env Vars if flags are empty
@@ -306,6 +371,12 @@ This is the recommended way to configure the Windows Service via Registry
 		if expIntID == "" {
 			expIntID = os.Getenv("AVIGILON_INTEGRATION_ID")
 		}
+		expTLS.applyFallbacks()
+		// Resolve the global settings before they are passed on to the service
+		cfg, err := newClientConfig("", client.TLSConfig{})
+		if err != nil {
+			return err
+		}
 
 		// Handle Port override
 		if envPort := os.Getenv("AVIGILON_PORT"); envPort != "" && expPort == "9100" {
@@ -320,20 +391,7 @@ This is the recommended way to configure the Windows Service via Registry
 		}
 
 		// ---------------------------------------------------------
-		// 2. Prepare Client Configuration
-		// ---------------------------------------------------------
-		hostClean := strings.TrimRight(expHost, "/")
-		cfg := client.ClientConfig{
-			BaseURL:       hostClean,
-			Username:      expUser,
-			Password:      expPass,
-			UserNonce:     expNonce,
-			UserKey:       expKey,
-			IntegrationID: expIntID,
-		}
-
-		// ---------------------------------------------------------
-		// 3. Define Service Configuration & Arguments
+		// 2. Define Service Configuration & Arguments
 		// ---------------------------------------------------------
 
 		// dynamically build arguments based on what is currently set.
@@ -365,6 +423,20 @@ This is the recommended way to configure the Windows Service via Registry
 		if expPort != "9100" {
 			svcArgs = append(svcArgs, "--port", expPort)
 		}
+		if cmd.Flags().Changed("timeout") {
+			svcArgs = append(svcArgs, "--timeout", cfg.Timeout.String())
+		}
+		if cfg.CompensateSkew {
+			svcArgs = append(svcArgs, "--compensate-skew")
+		}
+		// The service may run as another user, so point it at the same config file
+		if cfgFile != "" {
+			svcArgs = append(svcArgs, "--config", cfgFile)
+		}
+		if profileName != "" {
+			svcArgs = append(svcArgs, "--profile", profileName)
+		}
+		svcArgs = append(svcArgs, expTLS.args()...)
 
 		svcConfig := &service.Config{
 			Name:        "avigilon-exporter",
@@ -373,13 +445,42 @@ This is the recommended way to configure the Windows Service via Registry
 			Arguments:   svcArgs,
 		}
 
+		// ---------------------------------------------------------
+		// 3. Prepare Client Configuration
+		//    Profile settings are applied after the service arguments
+		//    are built so saved credentials and TLS settings never
+		//    end up in them.
+		// ---------------------------------------------------------
+		applyProfileFallbacks(cmd)
+
+		hostClean := strings.TrimRight(expHost, "/")
+		cfg.BaseURL = hostClean
+		cfg.TLS = expTLS.config()
+		cfg.Username = expUser
+		cfg.Password = expPass
+		cfg.UserNonce = expNonce
+		cfg.UserKey = expKey
+		cfg.IntegrationID = expIntID
+
+		// Only the profile's own host may have its pin updated
+		learned := pinFingerprint
+		if hostClean != viper.GetString(config.Key("base_url")) {
+			learned = func(fingerprint string) {
+				log.Printf("Trusting server certificate of %s on first use (SHA-256 %s); not pinned, the host isn't the profile's", hostClean, fingerprint)
+			}
+		}
+		api, err := newLoginClient(cfg, learned)
+		if err != nil {
+			return err
+		}
+
 		prg := &program{
-			api: client.New(cfg),
+			api: api,
 		}
 
 		s, err := service.New(prg, svcConfig)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 
 		// ---------------------------------------------------------
@@ -403,10 +504,10 @@ This is the recommended way to configure the Windows Service via Registry
 
 			err = service.Control(s, serviceAction)
 			if err != nil {
-				log.Fatalf("Failed to %s service: %v", serviceAction, err)
+				return fmt.Errorf("failed to %s service: %w", serviceAction, err)
 			}
 			fmt.Printf("Service action '%s' completed successfully.\n", serviceAction)
-			return
+			return nil
 		}
 
 		// ---------------------------------------------------------
@@ -416,16 +517,20 @@ This is the recommended way to configure the Windows Service via Registry
 		// STRICT VALIDATION: If we are actually trying to run (not just install),
 		// we must have credentials now (either from Flags or Env/Registry).
 		if expHost == "" || expPass == "" || expNonce == "" || expKey == "" {
-			log.Fatal("Fatal Error: Missing required credentials.\nPlease provide flags or set AVIGILON_* environment variables.")
+			return errors.New("missing required credentials\nPlease provide flags or set AVIGILON_* environment variables.")
 		}
 
 		logger, err := s.Logger(nil)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 		if err = s.Run(); err != nil {
-			logger.Error(err)
+			// The service manager only sees its own log
+			_ = logger.Error(err)
+			return err
 		}
+
+		return nil
 	},
 }
 
@@ -438,6 +543,7 @@ func init() {
 	exporterCmd.Flags().StringVar(&expKey, "key", "", "User Key")
 	exporterCmd.Flags().StringVar(&expIntID, "integration-id", "", "Integration ID")
 	exporterCmd.Flags().StringVar(&expPort, "port", "9100", "Port to listen on")
+	expTLS.register(exporterCmd.Flags())
 
 	// Service Control Flag
 	exporterCmd.Flags().StringVar(&serviceAction, "service", "", "Service action: install, uninstall, start, stop")
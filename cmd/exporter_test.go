@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"avigilon-cli/internal/client"
+	"avigilon-cli/pkg/avigilon"
+	"avigilon-cli/pkg/models"
+)
+
+// scrape returns the metrics page of an exporter for api.
+func scrape(t *testing.T, api loginAPI) string {
+	t.Helper()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	p := &program{api: api}
+	rec := httptest.NewRecorder()
+	p.metricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	return rec.Body.String()
+}
+
+func TestExporterMetrics(t *testing.T) {
+	api := testSite()
+	api.Alarms = []models.Alarm{{ID: "a1", State: "ACTIVE"}, {ID: "a2", State: "ACTIVE"}}
+	api.Skew = -3 * time.Second
+
+	body := scrape(t, api)
+	for _, want := range []string{
+		"avigilon_up 1",
+		"avigilon_system_health 1",
+		"avigilon_servers_total 2",
+		`avigilon_cameras_total{state="CONNECTED"} 2`,
+		`avigilon_cameras_total{state="DISCONNECTED"} 1`,
+		`avigilon_camera_up{id="cam-3",ip="10.0.2.10",model="H4SL",name="Parking"} 0`,
+		`avigilon_alarms_total{state="ACTIVE"} 2`,
+		"avigilon_clock_skew_seconds -3",
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("metrics lack %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestExporterMetricsFailure(t *testing.T) {
+	api := testSite()
+	api.Errors["GetCameras"] = &avigilon.APIError{StatusCode: 503}
+
+	if body := scrape(t, api); !strings.Contains(body, "avigilon_up 0\n") {
+		t.Errorf("failed scrape not reported:\n%s", body)
+	}
+}
+
+// TestExporterProfileHost checks which settings of the profile the exporter
+// takes over, using an unknown service action to stop before it runs.
+func TestExporterProfileHost(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		wantTLS  client.TLSConfig
+		wantPass string
+	}{
+		{"profile host", "", client.TLSConfig{Fingerprint: "ab:cd", Insecure: true}, "saved"},
+		{"other host", "https://other.example.com", client.TLSConfig{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testHome(t, map[string]string{
+				".avigilon-cli.yaml": `profiles:
+  default:
+    base_url: https://acc.example.com
+    tls:
+      fingerprint: ab:cd
+      insecure: true
+`,
+				".avigilon-cli.credentials.json": `{"username":"admin","password":"saved","nonce":"n","key":"k"}`,
+			})
+			for _, env := range []string{"AVIGILON_HOST", "AVIGILON_PASSWORD", "AVIGILON_TLS_FINGERPRINT", "AVIGILON_INSECURE"} {
+				t.Setenv(env, "")
+			}
+			configs := useLoginClient(t, testSite())
+
+			args := []string{"exporter", "--service", "bogus"}
+			if tt.host != "" {
+				args = append(args, "--host", tt.host)
+			}
+			_, err := execCLI(t, nil, args...)
+			if err == nil || !strings.Contains(err.Error(), "failed to bogus service") {
+				t.Fatalf("got error %v, want the unknown service action", err)
+			}
+
+			if len(*configs) != 1 {
+				t.Fatalf("%d clients built, want 1", len(*configs))
+			}
+			cfg := (*configs)[0]
+			if cfg.TLS != tt.wantTLS {
+				t.Errorf("TLS %+v, want %+v", cfg.TLS, tt.wantTLS)
+			}
+			if cfg.Password != tt.wantPass {
+				t.Errorf("password %q, want %q", cfg.Password, tt.wantPass)
+			}
+		})
+	}
+}
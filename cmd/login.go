@@ -1,8 +1,9 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -19,6 +20,13 @@ var (
 	nonce  string
 	key    string
 	intID  string
+
+	passStdin bool
+	passFile  string
+
+	saveCreds bool
+	credStore string
+	loginTLS  tlsFlags
 )
 
 // loginCmd represents the login command
@@ -28,50 +36,145 @@ var loginCmd = &cobra.Command{
 	Long: `Authenticates using the provided credentials, generates the required 
 cryptographic signature, and saves the session token locally for future commands.
 
+The host, username, TLS settings and session are stored in the profile selected
+with --profile or AVIGILON_PROFILE (default: the current profile), so logging in
+with a new profile name adds another site.
+
+Secrets left out of the command line are read from AVIGILON_PASSWORD,
+AVIGILON_NONCE and AVIGILON_KEY, or prompted for on the terminal. Use
+--password-stdin or --password-file to keep the password out of shell history.
+
+Saved credentials go to the store chosen with --credential-store:
+  auto       OS keyring if available, otherwise nothing is saved (default)
+  keyring    freedesktop Secret Service / macOS Keychain / Windows Credential Manager
+  encrypted  age file protected by a passphrase (AVIGILON_CREDENTIALS_PASSPHRASE or prompt)
+  file       plain JSON file, mode 0600 (only when asked for)
+
 Example:
-  avigilon-cli login --host "https://10.0.0.5/mt/api/rest/v1" --username admin --password pass --nonce myNonce --key myKey`,
-	Run: func(cmd *cobra.Command, args []string) {
+  avigilon-cli login --host "https://10.0.0.5/mt/api/rest/v1" --username admin --nonce myNonce --key myKey
+  pass show acc/admin | avigilon-cli login --host "https://10.0.0.5/mt/api/rest/v1" --password-stdin --nonce myNonce --key myKey
+  avigilon-cli login --profile branch --host "https://10.1.0.5/mt/api/rest/v1" --password-file ~/.acc-pass --credential-store encrypted`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if replay != nil {
+			return errors.New("--replay can't be used with login; replayed commands don't need a session")
+		}
+		if err := config.ValidateProfileName(config.ActiveProfile()); err != nil {
+			return err
+		}
+
 		// Clean up input host (remove trailing slash if present)
 		host = strings.TrimRight(host, "/")
 
+		// Resolve secrets that were not passed as flags
+		var err error
+		pass, err = secretInput{name: "password", value: pass, file: passFile, stdin: passStdin, env: "AVIGILON_PASSWORD"}.resolve()
+		if err == nil {
+			nonce, err = secretInput{name: "nonce", value: nonce, env: "AVIGILON_NONCE"}.resolve()
+		}
+		if err == nil {
+			key, err = secretInput{name: "key", value: key, env: "AVIGILON_KEY"}.resolve()
+		}
+		if err != nil {
+			return err
+		}
+		if pass == "" || nonce == "" || key == "" {
+			return errors.New("password, nonce and key must not be empty")
+		}
+
 		// 1. Construct the configuration object from flags
-		cfg := client.ClientConfig{
-			BaseURL:       host,
-			Username:      user,
-			Password:      pass,
-			UserNonce:     nonce,
-			UserKey:       key,
-			IntegrationID: intID,
+		cfg, err := newClientConfig(host, loginTLS.config())
+		if err != nil {
+			return err
+		}
+		cfg.Username = user
+		cfg.Password = pass
+		cfg.UserNonce = nonce
+		cfg.UserKey = key
+		cfg.IntegrationID = intID
+
+		// Keep checking against the fingerprint pinned on first use for this host
+		if cfg.TLS.TrustOnFirstUse && cfg.TLS.Fingerprint == "" && viper.GetString(config.Key("base_url")) == host {
+			cfg.TLS.Fingerprint = viper.GetString(config.Key("tls.fingerprint"))
 		}
 
-		fmt.Printf("Authenticating against %s as user '%s'...\n", host, user)
+		fmt.Printf("Authenticating against %s as user '%s' (profile '%s')...\n", host, user, config.ActiveProfile())
 
 		// 2. Initialize Client
-		api := client.New(cfg)
+		api, err := newLoginClient(cfg, func(fingerprint string) {
+			fmt.Printf("Trusting server certificate on first use (SHA-256 %s)\n", fingerprint)
+			cfg.TLS.Fingerprint = fingerprint
+		})
+		if err != nil {
+			return err
+		}
 
 		// 3. Perform Login
 		// Note: This relies on internal/client/client.go Login() being updated to return (string, error)
-		sessionID, err := api.Login()
+		sessionID, err := api.LoginContext(cmd.Context())
+		if errors.Is(err, client.ErrCertificateChanged) {
+			fmt.Println("WARNING: The server certificate changed since it was first trusted.")
+			fmt.Println("If this is expected, re-run login with --tls-fingerprint set to the new fingerprint.")
+		}
 		if err != nil {
-			log.Fatalf("Fatal: Login failed: %v", err)
+			return fmt.Errorf("login failed: %w", err)
 		}
 
 		fmt.Println("Login successful. Saving configuration...")
 
 		// 4. Update Viper Configuration
 		// We save the Base URL so subsequent commands (like 'cameras') know where to connect.
-		viper.Set("base_url", host)
+		viper.Set(config.Key("base_url"), host)
+		viper.Set(config.Key("username"), user)
+		setTLSConfig(cfg.TLS)
+
+		// 5. Persist Credentials so expired sessions can be renewed without a manual login
+		//    (before the config write, which records the store used)
+		if saveCreds {
+			creds := config.Credentials{
+				Username:      user,
+				Password:      pass,
+				UserNonce:     nonce,
+				UserKey:       key,
+				IntegrationID: intID,
+			}
+			store, err := config.SaveCredentials(credentialStoreKind(), creds)
+			switch {
+			case errors.Is(err, config.ErrNoKeyring) && strings.EqualFold(credentialStoreKind(), config.StoreAuto):
+				fmt.Fprintln(os.Stderr, "Warning: No OS keyring available, credentials were not saved and expired sessions need a new login.")
+				fmt.Fprintln(os.Stderr, "         Use --credential-store encrypted, or --credential-store file to save them in plain text.")
+			case err != nil:
+				return fmt.Errorf("failed to save credentials: %w", err)
+			case store == config.StoreFile:
+				fmt.Fprintln(os.Stderr, "Warning: Credentials saved in plain text to a file readable only by you.")
+			}
+		}
 
-		// 5. Persist Session and Config to file
+		// 6. Persist Session and Config to file
 		// We use the helper from internal/config to handle file creation/writing
 		if err := config.SaveSession(sessionID); err != nil {
-			log.Fatalf("Failed to save configuration file: %v", err)
+			return fmt.Errorf("failed to save configuration file: %w", err)
 		}
 
 		fmt.Printf("Session saved. You can now run commands like './avigilon-cli cameras'.\n")
+		return nil
 	},
 }
 
+// credentialStoreKind picks the store for saved credentials from
+// --credential-store, AVIGILON_CREDENTIAL_STORE or the config file.
+func credentialStoreKind() string {
+	if credStore != "" {
+		return credStore
+	}
+	if env := os.Getenv("AVIGILON_CREDENTIAL_STORE"); env != "" {
+		return env
+	}
+	if saved := viper.GetString("credential_store"); saved != "" {
+		return saved
+	}
+	return config.StoreAuto
+}
+
 func init() {
 	rootCmd.AddCommand(loginCmd)
 
@@ -79,14 +182,17 @@ func init() {
 	// We use local flags because these are specific only to the login action.
 	loginCmd.Flags().StringVar(&host, "host", "", "API Base URL (e.g. https://192.168.1.50/mt/api/rest/v1)")
 	loginCmd.Flags().StringVarP(&user, "username", "u", "administrator", "ACC Username")
-	loginCmd.Flags().StringVarP(&pass, "password", "p", "", "ACC Password")
+	loginCmd.Flags().StringVarP(&pass, "password", "p", "", "ACC Password (prefer --password-stdin or --password-file)")
+	loginCmd.Flags().BoolVar(&passStdin, "password-stdin", false, "Read the password from the first line of stdin")
+	loginCmd.Flags().StringVar(&passFile, "password-file", "", "Read the password from a file")
 	loginCmd.Flags().StringVar(&nonce, "nonce", "", "User Nonce (from Avigilon Integrator Config)")
 	loginCmd.Flags().StringVar(&key, "key", "", "User Key (from Avigilon Integrator Config)")
 	loginCmd.Flags().StringVar(&intID, "integration-id", "", "Integration ID (optional, leave empty if not used)")
+	loginTLS.register(loginCmd.Flags())
+	loginCmd.Flags().BoolVar(&saveCreds, "save-credentials", true, "Save credentials so expired sessions are renewed automatically")
+	loginCmd.Flags().StringVar(&credStore, "credential-store", "", "Where to save credentials: auto, keyring, encrypted or file (default auto)")
 
 	// Mark required flags to ensure the user provides necessary info
 	_ = loginCmd.MarkFlagRequired("host")
-	_ = loginCmd.MarkFlagRequired("password")
-	_ = loginCmd.MarkFlagRequired("nonce")
-	_ = loginCmd.MarkFlagRequired("key")
+	loginCmd.MarkFlagsMutuallyExclusive("password", "password-stdin", "password-file")
 }
@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+	"avigilon-cli/pkg/avigilon/mockserver"
+)
+
+// Variables to hold flag values
+var (
+	mockFixture    string
+	mockListen     string
+	mockTLS        bool
+	mockSessionTTL time.Duration
+	mockClockSkew  time.Duration
+	mockQuiet      bool
+	mockExample    bool
+)
+
+// demoFixture is served when no --fixture is given; --example-fixture
+// prints it as a starting point.
+const demoFixture = `# Fixture for 'avigilon-cli mock-server'. Records use the API field names.
+credentials:
+  username: administrator
+  password: mock-password
+  nonce: mock-nonce
+  key: mock-key
+  integrationId: ""
+
+health: GOOD
+
+# Sessions expire after this much idle time (remove to keep them forever)
+sessionTTL: 30m
+
+sites:
+  - {id: site-1, name: Head Office}
+
+servers:
+  - {id: server-1, name: ACC-SERVER-01}
+
+cameras:
+  - id: cam-1
+    name: Lobby
+    model: H5A-DO-IR
+    serial: "12345678"
+    firmwareVersion: 4.28.0.32
+    connectionState: CONNECTED
+    ipAddress: 10.0.0.11
+    serverId: server-1
+    location: Main entrance
+    logicalId: 1
+    macAddress: 00:18:85:00:00:11
+    resolution: {width: 2592, height: 1944}
+    codec: H264
+    connected: true
+    recordedData: true
+  - id: cam-2
+    name: Parking
+    model: H4SL-BO1-IR
+    serial: "87654321"
+    firmwareVersion: 4.24.0.18
+    connectionState: DISCONNECTED
+    ipAddress: 10.0.0.12
+    serverId: server-1
+    connected: false
+    recordedData: true
+
+alarms:
+  - {id: alarm-1, name: Door Forced, state: ACTIVE, timeOfMostRecentActivation: "2024-01-01T08:00:00.000Z"}
+
+# Event history per server ID; timestamps may be relative to startup
+events:
+  server-1:
+    - {thisId: evt-1, type: DEVICE_MOTION_START, timestamp: "-2h", originatingServerName: ACC-SERVER-01, cameraId: cam-1}
+    - {thisId: evt-2, type: DEVICE_MOTION_STOP, timestamp: "-119m", originatingServerName: ACC-SERVER-01, cameraId: cam-1}
+    - {thisId: evt-3, type: USER_LOGIN, timestamp: "-30m", originatingServerName: ACC-SERVER-01, userName: administrator}
+
+# JPEG files returned by /media, relative to this file
+# snapshots:
+#   cam-1: lobby.jpg
+
+# Injected failures: status to answer with, every n-th or a random rate of
+# matching requests, and an optional delay
+# failures:
+#   - {method: GET, path: /alarms, status: 503, every: 3}
+#   - {path: /media, delay: 2s}
+`
+
+// mockServerCmd represents the mock-server command
+var mockServerCmd = &cobra.Command{
+	Use:   "mock-server",
+	Short: "Run a mock Web Endpoint for offline development",
+	Long: `Serves the Web Endpoint REST API from a YAML or JSON fixture, so integrations
+can be developed without a VMS. Without --fixture a small demo site is served;
+--example-fixture prints it as a starting point.
+
+Logins are verified like on a real server: username, password, and an
+authorization token signed with the fixture's nonce and key within 5 minutes
+of the server clock. Other endpoints need the session from /login. Sessions
+expire after the fixture's sessionTTL (or --session-ttl) without use, and
+the fixture's failures section injects errors and delays.
+
+Registered webhooks receive heartbeats at their configured frequency, and
+an event for every call to the mock-only endpoint:
+
+  curl -X POST <url>/mock/events -d '{"serverId": "server-1",
+    "event": {"type": "DEVICE_MOTION_START", "cameraId": "cam-1"}}'`,
+	Example: `  avigilon-cli mock-server
+  avigilon-cli mock-server --example-fixture > site.yaml
+  avigilon-cli mock-server --fixture site.yaml --listen 127.0.0.1:8443 --tls`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if mockExample {
+			fmt.Print(demoFixture)
+			return nil
+		}
+
+		// 1. Load the fixture
+		var fixture *mockserver.Fixture
+		var err error
+		if mockFixture != "" {
+			fixture, err = mockserver.LoadFixture(mockFixture)
+		} else {
+			fixture, err = mockserver.ParseFixture([]byte(demoFixture), ".")
+		}
+		if err != nil {
+			return fmt.Errorf("loading fixture: %w", err)
+		}
+		data, opts, err := fixture.Build(time.Now())
+		if err != nil {
+			return fmt.Errorf("fixture: %w", err)
+		}
+
+		if cmd.Flags().Changed("session-ttl") {
+			opts.SessionTTL = mockSessionTTL
+		}
+		if mockClockSkew != 0 {
+			opts.Clock = func() time.Time { return time.Now().Add(mockClockSkew) }
+			data.Clock = opts.Clock
+		}
+		opts.Logf = log.Printf
+
+		// 2. Listen
+		ln, err := net.Listen("tcp", mockListen)
+		if err != nil {
+			return err
+		}
+
+		handler := mockserver.NewHandler(data, opts)
+		srv := &http.Server{Handler: handler}
+		if !mockQuiet {
+			srv.Handler = logRequests(handler)
+		}
+
+		scheme := "http"
+		if mockTLS {
+			host, _, _ := net.SplitHostPort(ln.Addr().String())
+			cert, err := mockserver.SelfSignedCertificate("localhost", host)
+			if err != nil {
+				return fmt.Errorf("creating certificate: %w", err)
+			}
+			srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+			ln = tls.NewListener(ln, srv.TLSConfig)
+			scheme = "https"
+		}
+		url := fmt.Sprintf("%s://%s", scheme, ln.Addr())
+
+		insecure := ""
+		if mockTLS {
+			insecure = " --tofu"
+		}
+		fmt.Printf("Mock Web Endpoint listening on %s (Ctrl+C to stop)\n\n", url)
+		fmt.Println("Log in with:")
+		fmt.Printf("  avigilon-cli login --profile mock --host %s%s \\\n", url, insecure)
+		fmt.Printf("    --username %q --password %q --nonce %q --key %q\n\n",
+			opts.Username, opts.Password, opts.UserNonce, opts.UserKey)
+
+		// 3. Serve until interrupted
+		ctx := cmd.Context()
+		go handler.RunHeartbeats(ctx)
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(shutdownCtx)
+		}()
+
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+
+		return nil
+	},
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests logs one line per request handled by next.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		log.Printf("%s %s %d (%s)", r.Method, r.URL.Path, rec.status, time.Since(start).Round(time.Millisecond))
+	})
+}
+
+func init() {
+	rootCmd.AddCommand(mockServerCmd)
+
+	mockServerCmd.Flags().StringVar(&mockFixture, "fixture", "", "YAML or JSON fixture to serve (default: built-in demo site)")
+	mockServerCmd.Flags().StringVar(&mockListen, "listen", "127.0.0.1:8080", "Address to listen on")
+	mockServerCmd.Flags().BoolVar(&mockTLS, "tls", false, "Serve HTTPS with a self-signed certificate")
+	mockServerCmd.Flags().DurationVar(&mockSessionTTL, "session-ttl", 0, "Expire sessions idle this long (overrides the fixture; 0 never expires)")
+	mockServerCmd.Flags().DurationVar(&mockClockSkew, "clock-skew", 0, "Run the server clock ahead (or behind, if negative) of the local one")
+	mockServerCmd.Flags().BoolVar(&mockQuiet, "quiet", false, "Don't log requests")
+	mockServerCmd.Flags().BoolVar(&mockExample, "example-fixture", false, "Print the demo fixture and exit")
+}
@@ -1,17 +1,16 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
-	"avigilon-cli/internal/client"
 )
 
 var (
 	outputTargetID string
 	outputIsCamera bool
+	outputCameras  cameraSelector
 )
 
 // Parent Command
@@ -25,34 +24,48 @@ var outputsCmd = &cobra.Command{
 var outputsTriggerCmd = &cobra.Command{
 	Use:   "trigger",
 	Short: "Trigger a digital output",
+	Long: `Trigger a digital output entity by --id, or all outputs of a camera given by
+--id with --camera, or by filters that match exactly one camera.`,
 	Example: `  avigilon-cli outputs trigger --id "camera_id_here" --camera
-  avigilon-cli outputs trigger --id "specific_output_entity_id"`,
-	Run: func(cmd *cobra.Command, args []string) {
-		baseUrl := viper.GetString("base_url")
-		session := viper.GetString("session_id")
-
-		if baseUrl == "" || session == "" {
-			fmt.Println("Error: Not logged in. Please run 'avigilon-cli login' first.")
-			os.Exit(1)
+  avigilon-cli outputs trigger --id "specific_output_entity_id"
+  avigilon-cli outputs trigger --name "Gate Camera"`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		api, err := newAPIClient()
+		if err != nil {
+			return err
 		}
 
-		api := client.New(client.ClientConfig{BaseURL: baseUrl})
-		api.HTTP.SetHeader("x-avg-session", session)
+		target := outputTargetID
+		if outputCameras.hasFilters() {
+			// Filters always select a camera
+			if outputTargetID != "" {
+				outputCameras.IDs = []string{outputTargetID}
+			}
+			camera, err := outputCameras.resolveOne(cmd.Context(), api)
+			if err != nil {
+				return err
+			}
+			outputTargetID = camera.ID
+			outputIsCamera = true
+			target = cameraLabel(camera)
+		} else if outputTargetID == "" {
+			return errors.New("give the output or camera --id, or camera filters such as --name")
+		}
 
 		targetType := "Digital Output Entity"
 		if outputIsCamera {
 			targetType = "All Outputs on Camera"
 		}
 
-		fmt.Printf("Triggering %s (%s)...\n", targetType, outputTargetID)
+		fmt.Printf("Triggering %s (%s)...\n", targetType, target)
 
-		err := api.TriggerDigitalOutput(session, outputTargetID, outputIsCamera)
+		err = api.TriggerDigitalOutputContext(cmd.Context(), outputTargetID, outputIsCamera)
 		if err != nil {
-			fmt.Printf("Error triggering output: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("triggering output: %w", err)
 		}
 
 		fmt.Println("Output triggered successfully.")
+		return nil
 	},
 }
 
@@ -62,6 +75,5 @@ func init() {
 
 	outputsTriggerCmd.Flags().StringVar(&outputTargetID, "id", "", "ID of the Camera or Digital Output")
 	outputsTriggerCmd.Flags().BoolVar(&outputIsCamera, "camera", false, "Set this flag if the ID provided is a Camera ID (triggers all attached outputs)")
-	
-	_ = outputsTriggerCmd.MarkFlagRequired("id")
+	outputCameras.addFilterFlags(outputsTriggerCmd.Flags())
 }
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"avigilon-cli/internal/client"
+	"avigilon-cli/internal/config"
+	"avigilon-cli/internal/output"
+)
+
+// profileInfo describes one profile for 'profile list' and 'profile show'.
+type profileInfo struct {
+	Name     string     `json:"name"`
+	Current  bool       `json:"current"`
+	Host     string     `json:"host"`
+	Username string     `json:"username,omitempty"`
+	LoggedIn bool       `json:"loggedIn"`
+	Store    string     `json:"credentialStore,omitempty"`
+	TLS      profileTLS `json:"tls"`
+	Limits   struct {
+		Metadata profileLimit `json:"metadata"`
+		Media    profileLimit `json:"media"`
+	} `json:"limits"`
+}
+
+// profileLimit is a client.Limit as shown by 'profile show'.
+type profileLimit struct {
+	Rate        float64 `json:"rate"`
+	Burst       int     `json:"burst"`
+	MaxInFlight int     `json:"maxInFlight"`
+}
+
+func (l profileLimit) String() string {
+	rate := "unlimited"
+	if l.Rate > 0 {
+		rate = fmt.Sprintf("%g/s (burst %d)", l.Rate, max(l.Burst, 1))
+	}
+	inFlight := "unlimited"
+	if l.MaxInFlight > 0 {
+		inFlight = strconv.Itoa(l.MaxInFlight)
+	}
+	return fmt.Sprintf("%s, %s in flight", rate, inFlight)
+}
+
+type profileTLS struct {
+	CAFile          string `json:"caFile,omitempty"`
+	CertFile        string `json:"certFile,omitempty"`
+	KeyFile         string `json:"keyFile,omitempty"`
+	Fingerprint     string `json:"fingerprint,omitempty"`
+	Insecure        bool   `json:"insecure,omitempty"`
+	TrustOnFirstUse bool   `json:"tofu,omitempty"`
+}
+
+func loadProfileInfo(name string) profileInfo {
+	tls := profileTLSConfig(name)
+	info := profileInfo{
+		Name:     name,
+		Current:  name == config.ActiveProfile(),
+		Host:     viper.GetString(config.ProfileKey(name, "base_url")),
+		Username: viper.GetString(config.ProfileKey(name, "username")),
+		LoggedIn: viper.GetString(config.ProfileKey(name, "session_id")) != "",
+		Store:    viper.GetString(config.ProfileKey(name, "credential_store")),
+		TLS: profileTLS{
+			CAFile:          tls.CAFile,
+			CertFile:        tls.CertFile,
+			KeyFile:         tls.KeyFile,
+			Fingerprint:     tls.Fingerprint,
+			Insecure:        tls.Insecure,
+			TrustOnFirstUse: tls.TrustOnFirstUse,
+		},
+	}
+
+	// Invalid limits are reported when a client is built
+	if limits, err := profileLimits(name); err == nil {
+		info.Limits.Metadata = profileLimit(limits.Metadata)
+		info.Limits.Media = profileLimit(limits.Media)
+	}
+	return info
+}
+
+// tlsSummary describes how a profile verifies the server certificate.
+func tlsSummary(cfg client.TLSConfig) string {
+	switch {
+	case cfg.Insecure:
+		return "insecure (verification disabled)"
+	case cfg.Fingerprint != "":
+		return "pinned SHA-256 " + cfg.Fingerprint
+	case cfg.TrustOnFirstUse:
+		return "trust on first use (not pinned yet)"
+	case cfg.CAFile != "":
+		return "verified against " + cfg.CAFile
+	}
+	return "verified against system CAs"
+}
+
+// profileColumns defines the table and CSV layout of 'profile list'
+var profileColumns = []output.Column[profileInfo]{
+	{Header: "CURRENT", Value: func(p profileInfo) string {
+		if p.Current {
+			return "*"
+		}
+		return ""
+	}},
+	{Header: "NAME", Value: func(p profileInfo) string { return p.Name }},
+	{Header: "HOST", Value: func(p profileInfo) string { return p.Host }},
+	{Header: "USERNAME", Value: func(p profileInfo) string { return p.Username }},
+	{Header: "SESSION", Wide: true, Value: func(p profileInfo) string {
+		if p.LoggedIn {
+			return "yes"
+		}
+		return "no"
+	}},
+}
+
+// Parent Command
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage site profiles",
+	Long: `Profiles keep the host, username, TLS settings and session of several
+ACC sites in one config file. Select one per command with --profile or
+AVIGILON_PROFILE, or make it the default with 'profile use'.
+
+A profile is created by logging in with a new name:
+  avigilon-cli login --profile branch --host "https://10.1.0.5/mt/api/rest/v1" ...`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all profiles",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names := config.Profiles()
+		if len(names) == 0 && outputOptions().IsTable() {
+			fmt.Println("No profiles found. Run 'avigilon-cli login' to create one.")
+			return nil
+		}
+
+		profiles := make([]profileInfo, 0, len(names))
+		for _, name := range names {
+			profiles = append(profiles, loadProfileInfo(name))
+		}
+		return printList(profiles, profileColumns)
+	},
+}
+
+var profileShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show the settings of a profile (default: the active one)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := config.ActiveProfile()
+		if len(args) == 1 {
+			name = strings.ToLower(args[0])
+		}
+		if !config.ProfileExists(name) {
+			return fmt.Errorf("profile %q does not exist. Run 'avigilon-cli login --profile %s' to create it", name, name)
+		}
+
+		info := loadProfileInfo(name)
+		opts := outputOptions()
+		if opts.IsDocument() {
+			if err := output.Encode(os.Stdout, opts.Format, info); err != nil {
+				return fmt.Errorf("encoding output: %w", err)
+			}
+			return nil
+		}
+		if !opts.IsTable() {
+			return printList([]profileInfo{info}, profileColumns)
+		}
+
+		session := "not logged in"
+		if info.LoggedIn {
+			session = "saved"
+		}
+		fmt.Printf("Profile:  %s\n", info.Name)
+		fmt.Printf("Current:  %t\n", info.Current)
+		fmt.Printf("Host:     %s\n", info.Host)
+		fmt.Printf("Username: %s\n", info.Username)
+		fmt.Printf("Session:  %s\n", session)
+		if info.Store != "" {
+			fmt.Printf("Secrets:  %s store\n", info.Store)
+		}
+		fmt.Printf("TLS:      %s\n", tlsSummary(profileTLSConfig(name)))
+		if info.TLS.CertFile != "" {
+			fmt.Printf("mTLS:     %s\n", info.TLS.CertFile)
+		}
+		fmt.Printf("Limits:   metadata %s\n", info.Limits.Metadata)
+		fmt.Printf("          media %s\n", info.Limits.Media)
+		return nil
+	},
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Make a profile the default for future commands",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.UseProfile(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Switched to profile '%s'.\n", args[0])
+		if os.Getenv("AVIGILON_PROFILE") != "" {
+			fmt.Fprintln(os.Stderr, "Note: AVIGILON_PROFILE is set and takes precedence in this shell.")
+		}
+
+		return nil
+	},
+}
+
+var profileDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a profile and its saved credentials",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.DeleteProfile(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Profile '%s' deleted.\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileShowCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileDeleteCmd)
+}
@@ -1,36 +1,166 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"time"
 
 	"github.com/spf13/cobra"
+	"avigilon-cli/internal/client"
 	"avigilon-cli/internal/config"
+	"avigilon-cli/internal/output"
+)
+
+// Exit codes let scripts tell API failures apart without parsing messages.
+const (
+	exitGeneric           = 1
+	exitUnauthorized      = 3
+	exitNotFound          = 4
+	exitRateLimited       = 5
+	exitServerUnavailable = 6
+	exitAPIError          = 7
 )
 
 var cfgFile string
+var profileName string
 var jsonOutput bool 
 
+// Traffic capture and tracing flags, see setupMiddleware
+var (
+	recordDir string
+	replayDir string
+	verbose   bool
+)
+
+// Output flags shared by every list command
+var (
+	outputFormat  string
+	outputNoHead  bool
+	outputColumns []string
+	outputSortBy  string
+)
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "avigilon-cli",
 	Short: "A CLI for interacting with Avigilon Web Endpoint API",
 	Long: `Manage cameras, alarms, and users on your Avigilon Control Center 
 via the Web Endpoint Service.`,
+
+	// Execute prints errors itself, to map them onto exit codes
+	SilenceErrors: true,
 }
 
+// Execute runs the command line and exits with the code of the error the
+// command returned, if any. Commands never exit themselves; this is the only
+// place errors are printed and turned into exit codes.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	// Cancel in-flight API requests on Ctrl+C instead of waiting for them
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitCodeFor(err))
+	}
+}
+
+// exitCodeFor maps an error returned by a command onto a process exit code.
+func exitCodeFor(err error) int {
+	var apiErr *client.APIError
+
+	switch {
+	case errors.Is(err, client.ErrUnauthorized):
+		return exitUnauthorized
+	case errors.Is(err, client.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, client.ErrRateLimited):
+		return exitRateLimited
+	case errors.Is(err, client.ErrServerUnavailable):
+		return exitServerUnavailable
+	case errors.As(err, &apiErr):
+		return exitAPIError
+	}
+	return exitGeneric
+}
+
+// outputOptions collects the output flags. --json is kept as a shorthand
+// for --output json. They are validated before any command runs, see
+// parseOutputOptions.
+func outputOptions() output.Options {
+	opts, _ := parseOutputOptions()
+	return opts
+}
+
+func parseOutputOptions() (output.Options, error) {
+	format := outputFormat
+	if jsonOutput {
+		format = output.FormatJSON
+	}
+
+	opts, err := output.ParseOptions(format)
+	if err != nil {
+		return opts, err
 	}
+	opts.NoHeaders = outputNoHead
+	opts.Columns = outputColumns
+	opts.SortBy = outputSortBy
+	return opts, nil
+}
+
+// printList renders items in the format selected by the output flags.
+func printList[T any](items []T, cols []output.Column[T]) error {
+	if err := output.Print(os.Stdout, outputOptions(), items, cols); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	return nil
 }
 
 func init() {
-	cobra.OnInitialize(func() { config.InitConfig(cfgFile) })
+	cobra.OnInitialize(func() {
+		config.InitConfig(cfgFile)
+		config.SetProfile(profileName)
+	})
+
+	// Set here rather than in the literal, as it reads rootCmd's own flags
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		// The arguments parsed, so later errors aren't usage errors
+		cmd.SilenceUsage = true
+
+		if _, err := parseOutputOptions(); err != nil {
+			return err
+		}
+		return setupMiddleware()
+	}
 	
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.avigilon-cli.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Site profile to use (default $AVIGILON_PROFILE, then the current profile)")
 	
 	// Add the persistent flag here
-	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Output results as JSON")
+	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Output results as JSON (same as --output json)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "table", "Output format: table, wide, json, yaml, csv, ndjson, template=..., jsonpath=...")
+	rootCmd.PersistentFlags().BoolVar(&outputNoHead, "no-headers", false, "Omit the header row in table, wide and csv output")
+	rootCmd.PersistentFlags().StringSliceVar(&outputColumns, "columns", nil, "Comma separated columns to show in table and csv output (e.g. ID,NAME)")
+	rootCmd.PersistentFlags().StringVar(&outputSortBy, "sort-by", "", "Column to sort results by (e.g. NAME)")
+
+	// Request timeout can also be set via AVIGILON_TIMEOUT or "timeout" in the config file
+	rootCmd.PersistentFlags().Duration("timeout", 30*time.Second, "Timeout for each API request (e.g. 10s, 1m; 0 disables)")
+
+	// Transient failures of read-only requests are retried with backoff; also AVIGILON_MAX_ATTEMPTS or "max_attempts"
+	rootCmd.PersistentFlags().Int("max-attempts", client.DefaultRetryPolicy.MaxAttempts, "Tries for read-only API requests failing with a timeout, 429 or 502/503/504 (1 disables retries)")
+
+	// Sign logins with the server's clock when the local one can't be fixed
+	rootCmd.PersistentFlags().Bool("compensate-skew", false, "Correct login timestamps for the server clock offset (from its Date header)")
+
+	// Capture API traffic for bug reports, or replay such a capture offline
+	rootCmd.PersistentFlags().StringVar(&recordDir, "record", "", "Record every API request and response to this directory, with secrets redacted")
+	rootCmd.PersistentFlags().StringVar(&replayDir, "replay", "", "Answer API requests from a directory written by --record instead of the server")
+
+	// Request traces go to stderr; --debug can also be set via AVIGILON_DEBUG or "debug" in the config file
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Log every API request (method, path, status, latency, sizes) to stderr")
+	rootCmd.PersistentFlags().Bool("debug", false, "Like --verbose, plus query parameters and headers (secrets redacted)")
 }
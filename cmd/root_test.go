@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"avigilon-cli/internal/client"
+	"avigilon-cli/pkg/avigilon"
+)
+
+// runCLI runs the command line args against api, as Execute would, with
+// an empty HOME and returns what the command wrote to stdout.
+func runCLI(t *testing.T, api avigilon.API, args ...string) (string, error) {
+	t.Helper()
+	testHome(t, nil)
+	return execCLI(t, api, args...)
+}
+
+// testHome points HOME at a new directory holding files, keyed by their
+// name, e.g. the config file ".avigilon-cli.yaml".
+func testHome(t *testing.T, files map[string]string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AVIGILON_PROFILE", "")
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(home, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return home
+}
+
+// execCLI runs args like runCLI, in the HOME set up by testHome.
+func execCLI(t *testing.T, api avigilon.API, args ...string) (string, error) {
+	t.Helper()
+	return execCLIContext(context.Background(), t, api, args...)
+}
+
+// execCLIContext is execCLI with a context, e.g. to stop 'events follow'.
+func execCLIContext(ctx context.Context, t *testing.T, api avigilon.API, args ...string) (string, error) {
+	t.Helper()
+
+	// The config is read again from HOME, without what earlier runs left
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	orig := newAPIClient
+	newAPIClient = func() (avigilon.API, error) { return api, nil }
+	t.Cleanup(func() { newAPIClient = orig })
+
+	// Commands write to os.Stdout directly
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	stdout := os.Stdout
+	os.Stdout = out
+	defer func() { os.Stdout = stdout }()
+
+	resetFlags(rootCmd)
+	setContext(rootCmd, ctx)
+	for _, s := range []*cameraSelector{&listCameras, &getCameras, &snapshotCameras, &recordCameras, &outputCameras} {
+		s.IDs = nil
+	}
+	rootCmd.SetArgs(args)
+	rootCmd.SetErr(io.Discard)
+	runErr := rootCmd.ExecuteContext(ctx)
+
+	data, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), runErr
+}
+
+// useLoginClient makes newLoginClient return api and collects the configs
+// it is called with.
+func useLoginClient(t *testing.T, api loginAPI) *[]client.ClientConfig {
+	t.Helper()
+	var configs []client.ClientConfig
+	orig := newLoginClient
+	newLoginClient = func(cfg client.ClientConfig, learned func(string)) (loginAPI, error) {
+		configs = append(configs, cfg)
+		return api, nil
+	}
+	t.Cleanup(func() { newLoginClient = orig })
+	return &configs
+}
+
+// setContext gives cmd and its subcommands ctx, since cobra keeps the
+// context of the first run otherwise.
+func setContext(cmd *cobra.Command, ctx context.Context) {
+	cmd.SetContext(ctx)
+	for _, sub := range cmd.Commands() {
+		setContext(sub, ctx)
+	}
+}
+
+// resetFlags puts the flags of cmd and its subcommands back to their
+// defaults, since the flag variables outlive a single run.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if s, ok := f.Value.(pflag.SliceValue); ok {
+			_ = s.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
+
+func TestExitCodeFor(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errors.New("bad flag"), exitGeneric},
+		{fmt.Errorf("fetching cameras: %w", &client.APIError{StatusCode: 401}), exitUnauthorized},
+		{fmt.Errorf("getting snapshot: %w", &client.APIError{StatusCode: 404}), exitNotFound},
+		{&client.APIError{StatusCode: 429}, exitRateLimited},
+		{&client.APIError{StatusCode: 503}, exitServerUnavailable},
+		{&client.APIError{StatusCode: 400}, exitAPIError},
+	}
+	for _, tt := range tests {
+		if got := exitCodeFor(tt.err); got != tt.want {
+			t.Errorf("exitCodeFor(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+	"avigilon-cli/internal/config"
+)
+
+// secretInput resolves a secret from, in order: its flag, a file, stdin,
+// an environment variable, and finally an interactive prompt.
+type secretInput struct {
+	name  string // Shown in prompts and errors, e.g. "password"
+	value string
+	file  string
+	stdin bool
+	env   string
+}
+
+func (s secretInput) resolve() (string, error) {
+	switch {
+	case s.value != "":
+		return s.value, nil
+	case s.file != "":
+		data, err := os.ReadFile(s.file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s file: %w", s.name, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case s.stdin:
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("failed to read %s from stdin: %w", s.name, err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	if env := os.Getenv(s.env); env != "" {
+		return env, nil
+	}
+	secret, err := promptSecret(fmt.Sprintf("%s: ", strings.ToUpper(s.name[:1])+s.name[1:]))
+	if errors.Is(err, errNoTerminal) {
+		return "", fmt.Errorf("no %s given: pass --%s or set %s", s.name, s.name, s.env)
+	}
+	return secret, err
+}
+
+var errNoTerminal = errors.New("no terminal to prompt on")
+
+// promptSecret reads a line from the terminal without echoing it. Prompts
+// go to stderr so they don't end up in redirected output.
+func promptSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errNoTerminal
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return string(secret), err
+}
+
+// promptPassphrase unlocks the encrypted credential store interactively.
+func promptPassphrase(confirm bool) (string, error) {
+	passphrase, err := promptSecret("Credentials passphrase: ")
+	if err != nil || !confirm {
+		return passphrase, err
+	}
+
+	again, err := promptSecret("Repeat passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if again != passphrase {
+		return "", errors.New("passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+func init() {
+	config.PassphraseFunc = promptPassphrase
+}
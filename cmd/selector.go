@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"avigilon-cli/pkg/models"
+)
+
+// cameraSource is what a cameraSelector needs from the API.
+type cameraSource interface {
+	GetCamerasContext(ctx context.Context) ([]models.Camera, error)
+	GetServersContext(ctx context.Context) ([]models.Server, error)
+}
+
+// cameraSelector picks cameras by ID, name or attributes, so commands don't
+// need opaque IDs. Every given criterion must match; repeated --name
+// values match any of them.
+//
+// Names, models, states and servers are compared case-insensitively, as
+// exact values or globs ("Lobby*"). A name between slashes ("/^Door [0-9]+$/")
+// is a regular expression. --ip also takes a CIDR range.
+type cameraSelector struct {
+	IDs    []string
+	Names  []string
+	Model  string
+	IP     string
+	State  string
+	Server string
+}
+
+// addFilterFlags registers the attribute filters shared by all camera
+// commands; commands add their own ID flag.
+func (s *cameraSelector) addFilterFlags(flags *pflag.FlagSet) {
+	flags.StringArrayVar(&s.Names, "name", nil, "Camera name, glob (\"Lobby*\") or /regex/; repeat to match any")
+	flags.StringVar(&s.Model, "model", "", "Camera model or glob (e.g. \"H5A-*\")")
+	flags.StringVar(&s.IP, "ip", "", "Camera IP address, glob or CIDR range (e.g. 10.0.1.0/24)")
+	flags.StringVar(&s.State, "state", "", "Connection state (e.g. CONNECTED, DISCONNECTED)")
+	flags.StringVar(&s.Server, "server", "", "ID or name of the server the camera is connected to")
+}
+
+// hasFilters reports whether anything but IDs was given.
+func (s cameraSelector) hasFilters() bool {
+	return len(s.Names) > 0 || s.Model != "" || s.IP != "" || s.State != "" || s.Server != ""
+}
+
+// empty reports whether no criterion was given at all.
+func (s cameraSelector) empty() bool {
+	return len(s.IDs) == 0 && !s.hasFilters()
+}
+
+// filter returns the cameras matching every criterion.
+func (s cameraSelector) filter(ctx context.Context, api cameraSource, cameras []models.Camera) ([]models.Camera, error) {
+	names, err := s.nameMatchers()
+	if err != nil {
+		return nil, err
+	}
+	model, err := newMatcher(s.Model)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --model %q: %w", s.Model, err)
+	}
+	state, err := newMatcher(s.State)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --state %q: %w", s.State, err)
+	}
+	ip, err := newIPMatcher(s.IP)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --ip %q: %w", s.IP, err)
+	}
+	servers, err := s.serverIDs(ctx, api)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []models.Camera
+	for _, c := range cameras {
+		switch {
+		case len(s.IDs) > 0 && !slices.Contains(s.IDs, c.ID),
+			len(names) > 0 && !matchAny(names, c.Name),
+			!model.match(c.Model),
+			!state.match(c.ConnectionState),
+			!ip(c.IPAddress),
+			servers != nil && !servers[c.ServerID]:
+			continue
+		}
+		matched = append(matched, c)
+	}
+
+	return matched, nil
+}
+
+func (s cameraSelector) nameMatchers() ([]matcher, error) {
+	names := make([]matcher, 0, len(s.Names))
+	for _, n := range s.Names {
+		m, err := newMatcher(n)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --name %q: %w", n, err)
+		}
+		names = append(names, m)
+	}
+	return names, nil
+}
+
+// serverIDs resolves --server to the IDs of the matching servers, or nil
+// if no server was given.
+func (s cameraSelector) serverIDs(ctx context.Context, api cameraSource) (map[string]bool, error) {
+	if s.Server == "" {
+		return nil, nil
+	}
+	m, err := newMatcher(s.Server)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --server %q: %w", s.Server, err)
+	}
+
+	ids := map[string]bool{}
+	servers, err := api.GetServersContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, srv := range servers {
+		if m.match(srv.ID) || m.match(srv.Name) {
+			ids[srv.ID] = true
+		}
+	}
+	return ids, nil
+}
+
+// resolve returns the selected cameras, failing if there are none. IDs
+// given without other criteria are used as they are, without listing the
+// cameras first.
+func (s cameraSelector) resolve(ctx context.Context, api cameraSource) ([]models.Camera, error) {
+	if s.empty() {
+		return nil, fmt.Errorf("no cameras selected; use --id or filters such as --name")
+	}
+	if !s.hasFilters() {
+		cameras := make([]models.Camera, len(s.IDs))
+		for i, id := range s.IDs {
+			cameras[i] = models.Camera{ID: id}
+		}
+		return cameras, nil
+	}
+	return s.lookup(ctx, api)
+}
+
+// resolveOne returns the single selected camera, failing if the selection
+// matches several.
+func (s cameraSelector) resolveOne(ctx context.Context, api cameraSource) (models.Camera, error) {
+	cameras, err := s.resolve(ctx, api)
+	if err != nil {
+		return models.Camera{}, err
+	}
+	return single(cameras)
+}
+
+// lookup lists the cameras and returns the selected ones with all their
+// details. It fails if none match, or if an exact name matches more than
+// one camera, since the user meant a single one.
+func (s cameraSelector) lookup(ctx context.Context, api cameraSource) ([]models.Camera, error) {
+	all, err := api.GetCamerasContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cameras, err := s.filter(ctx, api, all)
+	if err != nil {
+		return nil, err
+	}
+	if len(cameras) == 0 {
+		return nil, fmt.Errorf("no camera matches the selection")
+	}
+
+	names, _ := s.nameMatchers()
+	for _, m := range names {
+		if !m.exact {
+			continue
+		}
+		var same []models.Camera
+		for _, c := range cameras {
+			if m.match(c.Name) {
+				same = append(same, c)
+			}
+		}
+		if len(same) > 1 {
+			return nil, fmt.Errorf("camera name %q is ambiguous, it matches %s; use --id or more filters", m.raw, describeCameras(same))
+		}
+	}
+	return cameras, nil
+}
+
+// single returns the only camera of a selection.
+func single(cameras []models.Camera) (models.Camera, error) {
+	if len(cameras) > 1 {
+		return models.Camera{}, fmt.Errorf("the selection matches %s; narrow it down to one camera", describeCameras(cameras))
+	}
+	return cameras[0], nil
+}
+
+// cameraLabel names a camera in messages: "Lobby (id)", or just the ID if
+// the name is unknown.
+func cameraLabel(c models.Camera) string {
+	if c.Name == "" {
+		return c.ID
+	}
+	return fmt.Sprintf("%s (%s)", c.Name, c.ID)
+}
+
+// describeCameras lists up to five cameras for an error message.
+func describeCameras(cameras []models.Camera) string {
+	const shown = 5
+	labels := make([]string, 0, shown)
+	for i, c := range cameras {
+		if i == shown {
+			labels = append(labels, fmt.Sprintf("and %d more", len(cameras)-shown))
+			break
+		}
+		labels = append(labels, cameraLabel(c))
+	}
+	return fmt.Sprintf("%d cameras: %s", len(cameras), strings.Join(labels, ", "))
+}
+
+// matcher compares a value with an exact string, a glob or a regular
+// expression. The zero matcher matches everything.
+type matcher struct {
+	raw   string
+	exact bool
+	re    *regexp.Regexp
+}
+
+func newMatcher(pattern string) (matcher, error) {
+	m := matcher{raw: pattern}
+	switch {
+	case pattern == "":
+	case len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/"):
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return matcher{}, err
+		}
+		m.re = re
+	case strings.ContainsAny(pattern, "*?["):
+		if _, err := path.Match(pattern, ""); err != nil {
+			return matcher{}, err
+		}
+	default:
+		m.exact = true
+	}
+	return m, nil
+}
+
+func (m matcher) match(value string) bool {
+	switch {
+	case m.raw == "":
+		return true
+	case m.re != nil:
+		return m.re.MatchString(value)
+	case m.exact:
+		return strings.EqualFold(m.raw, value)
+	}
+	// path.Match won't let * cross a slash, which camera names may contain
+	glob := strings.ReplaceAll(strings.ToLower(m.raw), "/", "\x00")
+	ok, _ := path.Match(glob, strings.ReplaceAll(strings.ToLower(value), "/", "\x00"))
+	return ok
+}
+
+func matchAny(matchers []matcher, value string) bool {
+	for _, m := range matchers {
+		if m.match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// newIPMatcher matches an address exactly, by glob or by CIDR range.
+func newIPMatcher(pattern string) (func(string) bool, error) {
+	if strings.Contains(pattern, "/") {
+		_, network, err := net.ParseCIDR(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return func(addr string) bool {
+			ip := net.ParseIP(addr)
+			return ip != nil && network.Contains(ip)
+		}, nil
+	}
+	m, err := newMatcher(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return m.match, nil
+}
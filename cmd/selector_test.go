@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"avigilon-cli/pkg/models"
+)
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{"", "anything", true},
+		{"lobby east", "Lobby East", true},
+		{"Lobby", "Lobby East", false},
+		{"Lobby*", "lobby west", true},
+		{"Door ?", "Door 10", false},
+		{"Door [0-9]", "Door 7", true},
+
+		// A glob's * crosses the slashes in names, and an escaped slash
+		// matches a literal one
+		{"Bldg A/*", "Bldg A/Door 1", true},
+		{"Bldg*", "Bldg A/Door 1", true},
+		{`Bldg A\/Door*`, "Bldg A/Door 1", true},
+		{`*\/Door 1`, "Bldg A/Door 1", true},
+		{`*\/Door 1`, "Bldg A Door 1", false},
+
+		// Between slashes is a case-sensitive regular expression
+		{"/^Door [0-9]+$/", "Door 12", true},
+		{"/^Door [0-9]+$/", "Door 12b", false},
+		{"/^door/", "Door 1", false},
+		{"/(?i)^door/", "Door 1", true},
+		{"/Bldg A/Door/", "Bldg A/Door 1", true},
+		{"//", "//", true},
+	}
+	for _, tt := range tests {
+		m, err := newMatcher(tt.pattern)
+		if err != nil {
+			t.Errorf("newMatcher(%q): %v", tt.pattern, err)
+			continue
+		}
+		if got := m.match(tt.value); got != tt.want {
+			t.Errorf("%q matches %q: %t, want %t", tt.pattern, tt.value, got, tt.want)
+		}
+	}
+
+	for _, pattern := range []string{"/(/", "Door [0-9"} {
+		if _, err := newMatcher(pattern); err == nil {
+			t.Errorf("newMatcher(%q) succeeded, want an error", pattern)
+		}
+	}
+}
+
+func TestIPMatcher(t *testing.T) {
+	tests := []struct {
+		pattern string
+		addr    string
+		want    bool
+	}{
+		{"10.0.1.0/24", "10.0.1.10", true},
+		{"10.0.1.0/24", "10.0.2.10", false},
+		{"10.0.1.0/24", "", false},
+		{"10.0.1.0/24", "camera.local", false},
+		{"fd00::/8", "fd00::10", true},
+
+		// Without a prefix length an address is exact, not a range
+		{"10.0.1.1", "10.0.1.1", true},
+		{"10.0.1.1", "10.0.1.10", false},
+		{"10.0.1.*", "10.0.1.10", true},
+	}
+	for _, tt := range tests {
+		match, err := newIPMatcher(tt.pattern)
+		if err != nil {
+			t.Errorf("newIPMatcher(%q): %v", tt.pattern, err)
+			continue
+		}
+		if got := match(tt.addr); got != tt.want {
+			t.Errorf("%q matches %q: %t, want %t", tt.pattern, tt.addr, got, tt.want)
+		}
+	}
+
+	for _, pattern := range []string{"10.0.1.0/33", "10.0.1/24"} {
+		if _, err := newIPMatcher(pattern); err == nil {
+			t.Errorf("newIPMatcher(%q) succeeded, want an error", pattern)
+		}
+	}
+}
+
+func TestSelectorServer(t *testing.T) {
+	api := testSite()
+	// A camera whose server isn't listed, with an ID that looks like a name
+	api.Cameras = append(api.Cameras, models.Camera{ID: "cam-4", Name: "Dock", ServerID: "ACC-03"})
+
+	tests := []struct {
+		server string
+		want   string
+	}{
+		{"srv-1", "cam-1,cam-2"},
+		{"acc-02", "cam-3"},
+		{"ACC-0*", "cam-1,cam-2,cam-3"},
+		{"/-02$/", "cam-3"},
+		{"ACC-03", ""},
+		{"nope", ""},
+	}
+	for _, tt := range tests {
+		s := cameraSelector{Server: tt.server}
+		cameras, err := s.filter(context.Background(), api, api.Cameras)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, c := range cameras {
+			ids = append(ids, c.ID)
+		}
+		if got := strings.Join(ids, ","); got != tt.want {
+			t.Errorf("--server %q selects %q, want %q", tt.server, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"avigilon-cli/internal/client"
+	"avigilon-cli/internal/config"
+	"avigilon-cli/internal/output"
+)
+
+// sessionInfo is printed by 'session show'.
+type sessionInfo struct {
+	Profile  string `json:"profile"`
+	Host     string `json:"host"`
+	Username string `json:"username,omitempty"`
+	Active   bool   `json:"active"`
+	Obtained string `json:"obtained,omitempty"`
+	LastUsed string `json:"lastUsed,omitempty"`
+	Renewal  bool   `json:"autoRenew"`
+}
+
+// logoutCmd represents the logout command
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "End the session on the server and forget it locally",
+	Long: `Calls the Web Endpoint logout endpoint for the active profile's session and
+removes the session from the config file. Saved credentials are kept; use
+'profile delete' to remove them as well.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn := resolveConnection()
+		if conn.BaseURL == "" || conn.Session == "" {
+			fmt.Printf("Not logged in (profile '%s').\n", conn.Profile)
+			return nil
+		}
+
+		api, err := conn.client()
+		if err != nil {
+			return err
+		}
+		err = api.LogoutContext(cmd.Context())
+		switch {
+		case err == nil:
+			fmt.Printf("Logged out of %s (profile '%s').\n", conn.BaseURL, conn.Profile)
+		case errors.Is(err, client.ErrUnauthorized):
+			fmt.Println("Session had already expired on the server.")
+		default:
+			// Still forget the session: it is useless to us once we give up on it
+			fmt.Fprintf(os.Stderr, "Warning: server logout failed, the session may stay open until it expires: %v\n", err)
+		}
+
+		// A session from AVIGILON_SESSION was never saved
+		if !conn.Saved {
+			return nil
+		}
+		if err := config.ClearSession(); err != nil {
+			return fmt.Errorf("failed to remove session from config: %w", err)
+		}
+
+		return nil
+	},
+}
+
+// Parent Command
+var sessionCmd = &cobra.Command{
+	Use:   "session",
+	Short: "Inspect or renew the stored session",
+}
+
+var sessionShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the stored session of the active profile",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		info := sessionInfo{
+			Profile:  config.ActiveProfile(),
+			Host:     viper.GetString(config.Key("base_url")),
+			Username: viper.GetString(config.Key("username")),
+			Active:   viper.GetString(config.Key("session_id")) != "",
+			Obtained: viper.GetString(config.Key("session_obtained")),
+			LastUsed: viper.GetString(config.Key("session_last_used")),
+			Renewal:  config.HasCredentials(),
+		}
+
+		if opts := outputOptions(); opts.IsDocument() {
+			if err := output.Encode(os.Stdout, opts.Format, info); err != nil {
+				return fmt.Errorf("encoding output: %w", err)
+			}
+			return nil
+		}
+
+		if !info.Active {
+			fmt.Printf("No session stored for profile '%s'. Run 'avigilon-cli login'.\n", info.Profile)
+			return nil
+		}
+		fmt.Printf("Profile:    %s\n", info.Profile)
+		fmt.Printf("Host:       %s\n", info.Host)
+		fmt.Printf("Username:   %s\n", info.Username)
+		fmt.Printf("Obtained:   %s\n", sessionTime(info.Obtained))
+		fmt.Printf("Last used:  %s\n", sessionTime(info.LastUsed))
+		if info.Renewal {
+			fmt.Println("Renewal:    automatic (credentials saved)")
+		} else {
+			fmt.Println("Renewal:    manual (run 'avigilon-cli login' when it expires)")
+		}
+
+		return nil
+	},
+}
+
+var sessionRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Log in again with the saved credentials and replace the session",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn := resolveConnection()
+		if conn.BaseURL == "" {
+			return errNotLoggedIn
+		}
+		if !conn.Saved || !config.HasCredentials() {
+			return errors.New("no saved credentials. Run 'avigilon-cli login' instead")
+		}
+
+		old := conn.Session
+		api, err := conn.client()
+		if err != nil {
+			return err
+		}
+		if err := api.EnsureCredentials(); err != nil {
+			return fmt.Errorf("failed to load credentials: %w", err)
+		}
+
+		sessionID, err := api.LoginContext(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("login failed: %w", err)
+		}
+		if err := config.SaveSession(sessionID); err != nil {
+			return fmt.Errorf("failed to save session: %w", err)
+		}
+
+		// End the replaced session so it doesn't linger on the server
+		if old != "" && old != sessionID {
+			if err := api.LogoutSessionContext(cmd.Context(), old); err != nil && !errors.Is(err, client.ErrUnauthorized) {
+				fmt.Fprintf(os.Stderr, "Warning: failed to end the previous session: %v\n", err)
+			}
+		}
+
+		fmt.Printf("Session refreshed for %s (profile '%s').\n", conn.BaseURL, conn.Profile)
+		return nil
+	},
+}
+
+// sessionTime renders a stored RFC3339 time in local time with its age.
+func sessionTime(raw string) string {
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return "unknown"
+	}
+	age := time.Since(t).Round(time.Second)
+	return fmt.Sprintf("%s (%s ago)", t.Local().Format("2006-01-02 15:04:05"), age)
+}
+
+func init() {
+	rootCmd.AddCommand(logoutCmd)
+	rootCmd.AddCommand(sessionCmd)
+	sessionCmd.AddCommand(sessionShowCmd)
+	sessionCmd.AddCommand(sessionRefreshCmd)
+}
@@ -2,43 +2,33 @@ package cmd
 
 import (
 	"fmt"
-	"os"
-	"text/tabwriter"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
-	"avigilon-cli/internal/client"
+	"avigilon-cli/internal/output"
+	"avigilon-cli/pkg/models"
 )
 
+// siteColumns defines the table and CSV layout of 'sites'
+var siteColumns = []output.Column[models.Site]{
+	{Header: "ID", Value: func(site models.Site) string { return site.ID }},
+	{Header: "NAME", Value: func(site models.Site) string { return site.Name }},
+}
+
 var sitesCmd = &cobra.Command{
 	Use:   "sites",
 	Short: "List all ACC Sites (Clusters)",
-	Run: func(cmd *cobra.Command, args []string) {
-		baseUrl := viper.GetString("base_url")
-		session := viper.GetString("session_id")
-
-		if baseUrl == "" || session == "" {
-			fmt.Println("Error: Not logged in. Please run 'avigilon-cli login' first.")
-			os.Exit(1)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		api, err := newAPIClient()
+		if err != nil {
+			return err
 		}
 
-		api := client.New(client.ClientConfig{BaseURL: baseUrl})
-		api.HTTP.SetHeader("x-avg-session", session)
-
-		sites, err := api.GetSites()
+		sites, err := api.GetSitesContext(cmd.Context())
 		if err != nil {
-			fmt.Printf("Error fetching sites: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("fetching sites: %w", err)
 		}
 
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-		fmt.Fprintln(w, "ID\tNAME")
-		fmt.Fprintln(w, "--\t----")
-
-		for _, site := range sites {
-			fmt.Fprintf(w, "%s\t%s\n", site.ID, site.Name)
-		}
-		w.Flush()
+		return printList(sites, siteColumns)
 	},
 }
 
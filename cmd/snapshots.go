@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"avigilon-cli/internal/client"
+	"avigilon-cli/internal/output"
+	"avigilon-cli/internal/timeparse"
+	"avigilon-cli/pkg/avigilon"
+	"avigilon-cli/pkg/models"
+)
+
+// snapshotSource is what bulk snapshots need from the API.
+type snapshotSource interface {
+	cameraSource
+	avigilon.MediaAPI
+}
+
+// Flags of the image taken
+var (
+	snapshotAt      string
+	snapshotTZ      string
+	snapshotWidth   int
+	snapshotHeight  int
+	snapshotQuality int
+)
+
+// Flags of bulk snapshots
+var (
+	snapshotAll          bool
+	snapshotDir          string
+	snapshotTemplate     string
+	snapshotManifestFile string
+	snapshotConcurrency  int
+	snapshotDisconnected bool
+)
+
+// defaultSnapshotTemplate names the files of bulk snapshots.
+const defaultSnapshotTemplate = "{name}_{id}_{timestamp}.jpg"
+
+// Status values of a snapshotResult
+const (
+	snapshotOK      = "ok"
+	snapshotFailed  = "failed"
+	snapshotSkipped = "skipped"
+)
+
+// snapshotResult is the manifest entry of one camera.
+type snapshotResult struct {
+	CameraID        string `json:"cameraId"`
+	Name            string `json:"name"`
+	ConnectionState string `json:"connectionState,omitempty"`
+	Status          string `json:"status"`
+	File            string `json:"file,omitempty"`
+	Bytes           int    `json:"bytes,omitempty"`
+	LatencyMs       int64  `json:"latencyMs"`
+	TakenAt         string `json:"takenAt,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// snapshotManifest describes a bulk snapshot run.
+type snapshotManifest struct {
+	StartedAt  time.Time        `json:"startedAt"`
+	FinishedAt time.Time        `json:"finishedAt"`
+	At         *time.Time       `json:"at,omitempty"` // Time of recorded frames, from --at
+	Directory  string           `json:"directory"`
+	Succeeded  int              `json:"succeeded"`
+	Failed     int              `json:"failed"`
+	Skipped    int              `json:"skipped"`
+	Cameras    []snapshotResult `json:"cameras"`
+}
+
+// snapshotOptions builds the image options from --at, --tz, --width,
+// --height and --quality.
+func snapshotOptions(now time.Time) (client.SnapshotOptions, error) {
+	opts := client.SnapshotOptions{Width: snapshotWidth, Height: snapshotHeight, Quality: snapshotQuality}
+	if snapshotAt != "" {
+		loc, err := timeparse.LoadLocation(snapshotTZ)
+		if err != nil {
+			return opts, err
+		}
+		if opts.At, err = timeparse.Parse(snapshotAt, now, loc); err != nil {
+			return opts, fmt.Errorf("--at: %w", err)
+		}
+	}
+	return opts, opts.Validate(now)
+}
+
+// runBulkSnapshots takes a snapshot of every selected camera, or of all
+// cameras with --all, writes them to --dir and a manifest next to them. It
+// returns an error if any snapshot failed. now is the clock of the file
+// names and the manifest.
+func runBulkSnapshots(ctx context.Context, api snapshotSource, opts client.SnapshotOptions, now func() time.Time) error {
+	if !snapshotAll && snapshotCameras.empty() {
+		return errors.New("select cameras with --all, --id or filters such as --name")
+	}
+
+	// 1. Select cameras. Their state is needed, so IDs are looked up too.
+	cameras, err := api.GetCamerasContext(ctx)
+	if err == nil && !snapshotCameras.empty() {
+		cameras, err = snapshotCameras.filter(ctx, api, cameras)
+	}
+	if err != nil {
+		return err
+	}
+	if len(cameras) == 0 {
+		return errors.New("no camera matches the selection")
+	}
+
+	dir := snapshotDir
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+
+	out := outputOptions()
+	quiet := out.IsDocument()
+	if !quiet {
+		fmt.Printf("Taking snapshots of %d cameras into %s ...\n", len(cameras), dir)
+	}
+
+	// 2. Fetch concurrently; the client's media limits apply on top
+	manifest := snapshotManifest{StartedAt: now().UTC(), Directory: dir}
+	if !opts.At.IsZero() {
+		at := opts.At.UTC()
+		manifest.At = &at
+	}
+	results := make([]snapshotResult, len(cameras))
+	files := newFileNamer(dir)
+	var printMu sync.Mutex
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < max(snapshotConcurrency, 1) && w < len(cameras); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = takeSnapshot(ctx, api, cameras[i], opts, files, now)
+				if !quiet {
+					printMu.Lock()
+					printSnapshotResult(cameras[i], results[i])
+					printMu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range cameras {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// 3. Write the manifest
+	manifest.FinishedAt = now().UTC()
+	manifest.Cameras = results
+	for _, r := range results {
+		switch r.Status {
+		case snapshotOK:
+			manifest.Succeeded++
+		case snapshotFailed:
+			manifest.Failed++
+		case snapshotSkipped:
+			manifest.Skipped++
+		}
+	}
+
+	manifestFile := snapshotManifestFile
+	if manifestFile == "" {
+		manifestFile = filepath.Join(dir, "manifest.json")
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err == nil {
+		err = os.WriteFile(manifestFile, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+
+	if quiet {
+		if err := output.Encode(os.Stdout, out.Format, manifest); err != nil {
+			return fmt.Errorf("encoding output: %w", err)
+		}
+	} else {
+		fmt.Printf("Saved %d snapshots (%d failed, %d skipped). Manifest: %s\n",
+			manifest.Succeeded, manifest.Failed, manifest.Skipped, manifestFile)
+	}
+	if manifest.Failed > 0 {
+		return fmt.Errorf("%d of %d snapshots failed", manifest.Failed, len(cameras))
+	}
+	return nil
+}
+
+// takeSnapshot fetches and saves the snapshot of one camera. Live
+// snapshots of disconnected cameras are skipped unless
+// --include-disconnected is given; recorded frames (--at) are always tried.
+func takeSnapshot(ctx context.Context, api avigilon.MediaAPI, c models.Camera, opts client.SnapshotOptions, files *fileNamer, now func() time.Time) snapshotResult {
+	r := snapshotResult{CameraID: c.ID, Name: c.Name, ConnectionState: c.ConnectionState}
+
+	// Cameras without a reported state are tried anyway
+	live := opts.At.IsZero()
+	if live && c.ConnectionState != "" && !strings.EqualFold(c.ConnectionState, "CONNECTED") && !snapshotDisconnected {
+		r.Status = snapshotSkipped
+		return r
+	}
+
+	taken, start := now(), time.Now()
+	img, err := api.GetSnapshotWithOptionsContext(ctx, c.ID, opts)
+	r.LatencyMs = time.Since(start).Milliseconds()
+	r.TakenAt = taken.UTC().Format(time.RFC3339)
+	if err == nil {
+		// {timestamp} is the time of the frame
+		frame := taken
+		if !live {
+			frame = opts.At
+		}
+		r.File, err = files.write(c, frame, img)
+	}
+	if err != nil {
+		r.Status = snapshotFailed
+		r.Error = err.Error()
+		return r
+	}
+
+	r.Status = snapshotOK
+	r.Bytes = len(img)
+	return r
+}
+
+func printSnapshotResult(c models.Camera, r snapshotResult) {
+	switch r.Status {
+	case snapshotOK:
+		fmt.Printf("  ok       %s -> %s (%d bytes, %dms)\n", cameraLabel(c), r.File, r.Bytes, r.LatencyMs)
+	case snapshotFailed:
+		fmt.Printf("  failed   %s: %s\n", cameraLabel(c), r.Error)
+	case snapshotSkipped:
+		fmt.Printf("  skipped  %s: %s\n", cameraLabel(c), r.ConnectionState)
+	}
+}
+
+// fileNamer renders --name-template into unique file names within dir.
+type fileNamer struct {
+	dir string
+
+	mu   sync.Mutex
+	used map[string]bool
+}
+
+func newFileNamer(dir string) *fileNamer {
+	return &fileNamer{dir: dir, used: map[string]bool{}}
+}
+
+// unsafeFileChars are replaced in values substituted into file names.
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// write saves img under the name rendered for c and returns the path.
+// Placeholders: {name}, {id}, {timestamp}, {logicalId}, {server},
+// {location}. A name already used in this run gets a numeric suffix.
+func (n *fileNamer) write(c models.Camera, at time.Time, img []byte) (string, error) {
+	clean := func(s string) string {
+		return strings.Trim(unsafeFileChars.ReplaceAllString(s, "_"), "_")
+	}
+	name := strings.NewReplacer(
+		"{name}", clean(c.Name),
+		"{id}", clean(c.ID),
+		"{timestamp}", at.UTC().Format("20060102T150405Z"),
+		"{logicalId}", strconv.Itoa(c.LogicalID),
+		"{server}", clean(c.ServerID),
+		"{location}", clean(c.Location),
+	).Replace(snapshotTemplate)
+
+	path := filepath.Join(n.dir, name)
+	n.mu.Lock()
+	ext := filepath.Ext(path)
+	for i := 2; n.used[path]; i++ {
+		path = fmt.Sprintf("%s_%d%s", strings.TrimSuffix(filepath.Join(n.dir, name), ext), i, ext)
+	}
+	n.used[path] = true
+	n.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, img, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
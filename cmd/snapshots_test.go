@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"avigilon-cli/pkg/avigilon"
+)
+
+// readManifest decodes the manifest of a bulk snapshot run.
+func readManifest(t *testing.T, path string) snapshotManifest {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m snapshotManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestBulkSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	_, err := runCLI(t, testSite(), "cameras", "snapshot", "--all", "--dir", dir, "--name-template", "{name}.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := readManifest(t, filepath.Join(dir, "manifest.json"))
+	if m.Succeeded != 2 || m.Failed != 0 || m.Skipped != 1 {
+		t.Errorf("manifest counts %d ok, %d failed, %d skipped; want 2, 0, 1", m.Succeeded, m.Failed, m.Skipped)
+	}
+	status := map[string]string{}
+	for _, r := range m.Cameras {
+		status[r.CameraID] = r.Status
+	}
+	want := map[string]string{"cam-1": snapshotOK, "cam-2": snapshotOK, "cam-3": snapshotSkipped}
+	for id, s := range want {
+		if status[id] != s {
+			t.Errorf("%s: status %q, want %q", id, status[id], s)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, e := range entries {
+		files = append(files, e.Name())
+	}
+	sort.Strings(files)
+	if got := strings.Join(files, ","); got != "Lobby_East.jpg,Lobby_West.jpg,manifest.json" {
+		t.Errorf("files %s, want the two connected cameras and the manifest", got)
+	}
+}
+
+func TestBulkSnapshotsClock(t *testing.T) {
+	dir := t.TempDir()
+	api := testSite()
+	local := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	api.Clock = func() time.Time { return local }
+
+	// File names and the manifest use the API's clock
+	_, err := runCLI(t, api, "cameras", "snapshot", "--name", "Lobby East", "--dir", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Lobby_East_cam-1_20240501T120000Z.jpg")); err != nil {
+		t.Error(err)
+	}
+	m := readManifest(t, filepath.Join(dir, "manifest.json"))
+	if !m.StartedAt.Equal(local) || !m.FinishedAt.Equal(local) || m.Cameras[0].TakenAt != "2024-05-01T12:00:00Z" {
+		t.Errorf("manifest times %s, %s, %s; want %s", m.StartedAt, m.FinishedAt, m.Cameras[0].TakenAt, local)
+	}
+
+	// Recorded frames are named by their time, relative to the same clock
+	_, err = runCLI(t, api, "cameras", "snapshot", "--name", "Lobby East", "--dir", dir, "--at", "-2h", "--tz", "UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Lobby_East_cam-1_20240501T100000Z.jpg")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBulkSnapshotsIncludeDisconnected(t *testing.T) {
+	dir := t.TempDir()
+	_, err := runCLI(t, testSite(), "cameras", "snapshot", "--state", "DISCONNECTED", "--dir", dir, "--include-disconnected")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := readManifest(t, filepath.Join(dir, "manifest.json"))
+	if m.Succeeded != 1 || len(m.Cameras) != 1 || m.Cameras[0].CameraID != "cam-3" {
+		t.Errorf("manifest = %+v, want one snapshot of cam-3", m)
+	}
+}
+
+func TestBulkSnapshotsFailure(t *testing.T) {
+	dir := t.TempDir()
+	api := testSite()
+	api.Errors["GetSnapshot"] = &avigilon.APIError{StatusCode: 500, Message: "encoder busy"}
+
+	_, err := runCLI(t, api, "cameras", "snapshot", "--all", "--dir", dir)
+	if err == nil || !strings.Contains(err.Error(), "2 of 3 snapshots failed") {
+		t.Fatalf("got error %v, want 2 of 3 snapshots failed", err)
+	}
+
+	// The manifest is written even if snapshots failed
+	m := readManifest(t, filepath.Join(dir, "manifest.json"))
+	if m.Failed != 2 || m.Skipped != 1 {
+		t.Errorf("manifest counts %d failed, %d skipped; want 2, 1", m.Failed, m.Skipped)
+	}
+	for _, r := range m.Cameras {
+		if r.Status == snapshotFailed && !strings.Contains(r.Error, "encoder busy") {
+			t.Errorf("%s: error %q does not give the cause", r.CameraID, r.Error)
+		}
+	}
+}
+
+func TestBulkSnapshotsNeedSelection(t *testing.T) {
+	_, err := runCLI(t, testSite(), "cameras", "snapshot", "--dir", t.TempDir())
+	if err == nil {
+		t.Fatal("bulk snapshot without --all or filters succeeded, want an error")
+	}
+}
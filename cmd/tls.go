@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"avigilon-cli/internal/client"
+	"avigilon-cli/internal/config"
+)
+
+// tlsFlags holds the TLS options shared by 'login' and 'exporter'.
+type tlsFlags struct {
+	caFile      string
+	certFile    string
+	keyFile     string
+	fingerprint string
+	insecure    bool
+	tofu        bool
+}
+
+func (f *tlsFlags) register(fs *pflag.FlagSet) {
+	fs.StringVar(&f.caFile, "tls-ca", "", "PEM CA bundle used to verify the server certificate")
+	fs.StringVar(&f.certFile, "tls-cert", "", "Client certificate for mutual TLS")
+	fs.StringVar(&f.keyFile, "tls-key", "", "Private key for --tls-cert")
+	fs.StringVar(&f.fingerprint, "tls-fingerprint", "", "Pin the server certificate by SHA-256 fingerprint")
+	fs.BoolVar(&f.insecure, "insecure", false, "Skip TLS certificate verification (not recommended)")
+	fs.BoolVar(&f.tofu, "tofu", false, "Trust the server certificate on first use and pin its fingerprint")
+}
+
+func (f *tlsFlags) config() client.TLSConfig {
+	return client.TLSConfig{
+		CAFile:          f.caFile,
+		CertFile:        f.certFile,
+		KeyFile:         f.keyFile,
+		Fingerprint:     f.fingerprint,
+		Insecure:        f.insecure,
+		TrustOnFirstUse: f.tofu,
+	}
+}
+
+// savedTLSConfig returns the TLS settings stored in the active profile.
+func savedTLSConfig() client.TLSConfig {
+	return profileTLSConfig(config.ActiveProfile())
+}
+
+// profileTLSConfig returns the TLS settings stored in the named profile.
+func profileTLSConfig(profile string) client.TLSConfig {
+	return client.TLSConfig{
+		CAFile:          viper.GetString(config.ProfileKey(profile, "tls.ca_file")),
+		CertFile:        viper.GetString(config.ProfileKey(profile, "tls.cert_file")),
+		KeyFile:         viper.GetString(config.ProfileKey(profile, "tls.key_file")),
+		Fingerprint:     viper.GetString(config.ProfileKey(profile, "tls.fingerprint")),
+		Insecure:        viper.GetBool(config.ProfileKey(profile, "tls.insecure")),
+		TrustOnFirstUse: viper.GetBool(config.ProfileKey(profile, "tls.tofu")),
+	}
+}
+
+// setTLSConfig stages TLS settings for the next config write.
+func setTLSConfig(cfg client.TLSConfig) {
+	viper.Set(config.Key("tls.ca_file"), cfg.CAFile)
+	viper.Set(config.Key("tls.cert_file"), cfg.CertFile)
+	viper.Set(config.Key("tls.key_file"), cfg.KeyFile)
+	viper.Set(config.Key("tls.fingerprint"), cfg.Fingerprint)
+	viper.Set(config.Key("tls.insecure"), cfg.Insecure)
+	viper.Set(config.Key("tls.tofu"), cfg.TrustOnFirstUse)
+}
+
+// pinFingerprint records a certificate trusted on first use in the config file.
+func pinFingerprint(fingerprint string) {
+	fmt.Fprintf(os.Stderr, "Warning: trusting server certificate on first use (SHA-256 %s)\n", fingerprint)
+
+	viper.Set(config.Key("tls.fingerprint"), fingerprint)
+	if err := config.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save certificate fingerprint: %v\n", err)
+	}
+}
+
+// applyFallbacks fills options not given as flags from the AVIGILON_TLS_*
+// environment variables.
+func (f *tlsFlags) applyFallbacks() {
+	fill := func(dst *string, env string) {
+		if *dst == "" {
+			*dst = os.Getenv(env)
+		}
+	}
+	fill(&f.caFile, "AVIGILON_TLS_CA")
+	fill(&f.certFile, "AVIGILON_TLS_CERT")
+	fill(&f.keyFile, "AVIGILON_TLS_KEY")
+	fill(&f.fingerprint, "AVIGILON_TLS_FINGERPRINT")
+
+	if !f.insecure {
+		f.insecure = os.Getenv("AVIGILON_INSECURE") == "true"
+	}
+	if !f.tofu {
+		f.tofu = os.Getenv("AVIGILON_TOFU") == "true"
+	}
+}
+
+// applyProfile fills options still unset from the active profile, but only
+// if host is the profile's own: its pinned certificate or insecure setting
+// belongs to that server, as in resolveConnection.
+func (f *tlsFlags) applyProfile(host string) {
+	if strings.TrimRight(host, "/") != viper.GetString(config.Key("base_url")) {
+		return
+	}
+	saved := savedTLSConfig()
+
+	fill := func(dst *string, fallback string) {
+		if *dst == "" {
+			*dst = fallback
+		}
+	}
+	fill(&f.caFile, saved.CAFile)
+	fill(&f.certFile, saved.CertFile)
+	fill(&f.keyFile, saved.KeyFile)
+	fill(&f.fingerprint, saved.Fingerprint)
+
+	f.insecure = f.insecure || saved.Insecure
+	f.tofu = f.tofu || saved.TrustOnFirstUse
+}
+
+// args renders the options as command line flags, e.g. for service arguments.
+func (f *tlsFlags) args() []string {
+	var args []string
+	if f.caFile != "" {
+		args = append(args, "--tls-ca", f.caFile)
+	}
+	if f.certFile != "" {
+		args = append(args, "--tls-cert", f.certFile)
+	}
+	if f.keyFile != "" {
+		args = append(args, "--tls-key", f.keyFile)
+	}
+	if f.fingerprint != "" {
+		args = append(args, "--tls-fingerprint", f.fingerprint)
+	}
+	if f.insecure {
+		args = append(args, "--insecure")
+	}
+	if f.tofu {
+		args = append(args, "--tofu")
+	}
+	return args
+}
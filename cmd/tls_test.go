@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"avigilon-cli/internal/client"
+	"avigilon-cli/internal/config"
+)
+
+func TestTLSProfileFallbacks(t *testing.T) {
+	t.Setenv("AVIGILON_PROFILE", "")
+	t.Setenv("AVIGILON_TLS_CA", "")
+	t.Setenv("AVIGILON_TLS_FINGERPRINT", "")
+	t.Setenv("AVIGILON_INSECURE", "")
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set(config.Key("base_url"), "https://acc.example.com")
+	setTLSConfig(client.TLSConfig{CAFile: "/etc/acc-ca.pem", Fingerprint: "ab:cd", Insecure: true})
+
+	tests := []struct {
+		name string
+		host string
+		want client.TLSConfig
+	}{
+		{"profile host", "https://acc.example.com/", client.TLSConfig{CAFile: "/etc/acc-ca.pem", Fingerprint: "ab:cd", Insecure: true}},
+		{"other host", "https://other.example.com", client.TLSConfig{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f tlsFlags
+			f.applyFallbacks()
+			f.applyProfile(tt.host)
+			if got := f.config(); got != tt.want {
+				t.Errorf("TLS config %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Flags and environment still apply to another host
+	t.Setenv("AVIGILON_TLS_FINGERPRINT", "ef:01")
+	f := tlsFlags{caFile: "/etc/other-ca.pem"}
+	f.applyFallbacks()
+	f.applyProfile("https://other.example.com")
+	if want := (client.TLSConfig{CAFile: "/etc/other-ca.pem", Fingerprint: "ef:01"}); f.config() != want {
+		t.Errorf("TLS config %+v, want %+v", f.config(), want)
+	}
+}
@@ -1,15 +1,12 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
 	"strings"
-	"text/tabwriter"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
-	"avigilon-cli/internal/client"
+	"avigilon-cli/internal/output"
+	"avigilon-cli/pkg/models"
 )
 
 // Variables to hold flag values
@@ -22,20 +19,23 @@ var (
 	webhookHBFreq   int
 )
 
-// Helper to get authenticated client using stored config
-func getClient() *client.AvigilonClient {
-	baseUrl := viper.GetString("base_url")
-	session := viper.GetString("session_id")
-
-	if baseUrl == "" || session == "" {
-		fmt.Println("Error: Not logged in. Please run 'avigilon-cli login' first.")
-		os.Exit(1)
-	}
-
-	api := client.New(client.ClientConfig{BaseURL: baseUrl})
-	// Inject the session header for authentication
-	api.HTTP.SetHeader("x-avg-session", session)
-	return api
+// webhookColumns defines the table and CSV layout of 'webhooks list'
+var webhookColumns = []output.Column[models.Webhook]{
+	{Header: "ID", Value: func(h models.Webhook) string { return h.ID }},
+	{Header: "URL", Value: func(h models.Webhook) string { return h.URL }},
+	{Header: "TOPICS", Value: func(h models.Webhook) string {
+		// Safely access nested struct fields
+		if h.EventTopics != nil && len(h.EventTopics.Include) > 0 {
+			return strings.Join(h.EventTopics.Include, ",")
+		}
+		return "ALL"
+	}},
+	{Header: "HEARTBEAT", Wide: true, Value: func(h models.Webhook) string {
+		if h.Heartbeat == nil || !h.Heartbeat.Enable {
+			return "off"
+		}
+		return fmt.Sprintf("%dms", h.Heartbeat.FrequencyMs)
+	}},
 }
 
 // Parent Command
@@ -49,46 +49,23 @@ var webhooksCmd = &cobra.Command{
 var webhooksListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all registered webhooks",
-	Run: func(cmd *cobra.Command, args []string) {
-		api := getClient()
-		
-		hooks, err := api.GetWebhooks()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		api, err := newAPIClient()
 		if err != nil {
-			fmt.Printf("Error fetching webhooks: %v\n", err)
-			os.Exit(1)
+			return err
 		}
-
-		// --- JSON OUTPUT ---
-		if jsonOutput {
-			enc := json.NewEncoder(os.Stdout)
-			enc.SetIndent("", "  ")
-			if err := enc.Encode(hooks); err != nil {
-				fmt.Printf("Error encoding JSON: %v\n", err)
-				os.Exit(1)
-			}
-			return
+		
+		hooks, err := api.GetWebhooksContext(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("fetching webhooks: %w", err)
 		}
-		// -------------------
 
-		if len(hooks) == 0 {
+		if len(hooks) == 0 && outputOptions().IsTable() {
 			fmt.Println("No webhooks found.")
-			return
+			return nil
 		}
 
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-		fmt.Fprintln(w, "ID\tURL\tTOPICS")
-		fmt.Fprintln(w, "--\t---\t------")
-
-		for _, h := range hooks {
-			topics := "ALL"
-			// Safely access nested struct fields
-			if h.EventTopics != nil && len(h.EventTopics.Include) > 0 {
-				topics = strings.Join(h.EventTopics.Include, ",")
-			}
-			
-			fmt.Fprintf(w, "%s\t%s\t%s\n", h.ID, h.URL, topics)
-		}
-		w.Flush()
+		return printList(hooks, webhookColumns)
 	},
 }
 
@@ -99,14 +76,14 @@ var webhooksCreateCmd = &cobra.Command{
 	Example: `  avigilon-cli webhooks create --url "http://myserver.com/api" --topics "ALL"
   avigilon-cli webhooks create --url "http://myserver.com/api" --heartbeat=false
   avigilon-cli webhooks create --url "http://myserver.com/api" --token "my-custom-secret"`,
-	Run: func(cmd *cobra.Command, args []string) {
-		// 1. Get Session ID from config (Required for body payload)
-		session := viper.GetString("session_id")
-		
-		// 2. Initialize Client
-		api := getClient()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 1. Initialize Client (the session for the body payload comes from the client)
+		api, err := newAPIClient()
+		if err != nil {
+			return err
+		}
 		
-		// 3. Process Topics
+		// 2. Process Topics
 		topicsSlice := strings.Split(webhookTopics, ",")
 		// If empty or just whitespace, default to ALL
 		if len(topicsSlice) == 0 || (len(topicsSlice) == 1 && strings.TrimSpace(topicsSlice[0]) == "") {
@@ -121,14 +98,14 @@ var webhooksCreateCmd = &cobra.Command{
 		fmt.Printf("Creating webhook for URL: %s ...\n", webhookURL)
 		fmt.Printf("Configuration: Heartbeat=%t (%dms), Token=%s\n", webhookHBEnable, webhookHBFreq, webhookToken)
 
-		// 4. Call API with all parameters
-		err := api.CreateWebhook(session, webhookURL, webhookToken, topicsSlice, webhookHBEnable, webhookHBFreq)
+		// 3. Call API with all parameters
+		err = api.CreateWebhookContext(cmd.Context(), webhookURL, webhookToken, topicsSlice, webhookHBEnable, webhookHBFreq)
 		if err != nil {
-			fmt.Printf("Error creating webhook: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("creating webhook: %w", err)
 		}
 		
 		fmt.Println("Webhook created successfully.")
+		return nil
 	},
 }
 
@@ -137,17 +114,20 @@ var webhooksDeleteCmd = &cobra.Command{
 	Use:   "delete",
 	Short: "Delete a webhook by ID",
 	Example: `  avigilon-cli webhooks delete --id "webhook_id_string"`,
-	Run: func(cmd *cobra.Command, args []string) {
-		api := getClient()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		api, err := newAPIClient()
+		if err != nil {
+			return err
+		}
 		
 		fmt.Printf("Deleting webhook ID: %s ...\n", webhookID)
 		
-		err := api.DeleteWebhook(webhookID)
+		err = api.DeleteWebhookContext(cmd.Context(), webhookID)
 		if err != nil {
-			fmt.Printf("Error deleting webhook: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("deleting webhook: %w", err)
 		}
 		fmt.Println("Webhook deleted successfully.")
+		return nil
 	},
 }
 
@@ -8,11 +8,45 @@ import (
 	"time"
 )
 
+// Clock returns the current time. Signers take one so the timestamp can be
+// pinned in tests.
+type Clock func() time.Time
+
+// Signer generates login tokens for one user nonce and key.
+type Signer struct {
+	UserNonce     string
+	UserKey       string
+	IntegrationID string
+
+	Clock  Clock         // time.Now if nil
+	Offset time.Duration // Added to Clock, e.g. the measured server clock skew
+}
+
+// Now returns the time the signer stamps tokens with.
+func (s Signer) Now() time.Time {
+	now := time.Now
+	if s.Clock != nil {
+		now = s.Clock
+	}
+	return now().Add(s.Offset)
+}
+
+// Token creates the signature required for the Login endpoint.
+func (s Signer) Token() string {
+	return TokenAt(s.UserNonce, s.UserKey, s.IntegrationID, s.Now())
+}
+
 // GenerateAuthToken creates the signature required for the Login endpoint.
 func GenerateAuthToken(userNonce, userKey, integrationId string) string {
-	// 1. Get current Unix timestamp (Seconds)
 	// Ensure your system clock is synced! Time drift > 5-10 mins will cause 403s.
-	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	// Use a Signer with Offset set to compensate for a known drift.
+	return Signer{UserNonce: userNonce, UserKey: userKey, IntegrationID: integrationId}.Token()
+}
+
+// TokenAt creates the Login signature for the given time.
+func TokenAt(userNonce, userKey, integrationId string, at time.Time) string {
+	// 1. Get Unix timestamp (Seconds)
+	timestamp := strconv.FormatInt(at.Unix(), 10)
 
 	// 2. Construct the payload string: timestamp + userKey
 	payload := timestamp + userKey
@@ -23,7 +57,7 @@ func GenerateAuthToken(userNonce, userKey, integrationId string) string {
 
 	// 4. Construct final token string
 	// Format: userNonce:timestamp:hexEncodedHash[:integrationIdentifier]
-	
+
 	baseToken := fmt.Sprintf("%s:%s:%s", userNonce, timestamp, hexEncodedHash)
 
 	if integrationId != "" {
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// knownHash is the SHA-256 of "1700000000secret", computed independently.
+const knownHash = "0097dc146dfa5a8cadebac725e75c4e7800366597c7674eb36e8734b2f7fdc02"
+
+var knownTime = time.Unix(1700000000, 0)
+
+func TestTokenAt(t *testing.T) {
+	tests := []struct {
+		name          string
+		integrationID string
+		want          string
+	}{
+		{"without integration ID", "", "nonce:1700000000:" + knownHash},
+		{"with integration ID", "int-1", "nonce:1700000000:" + knownHash + ":int-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TokenAt("nonce", "secret", tt.integrationID, knownTime); got != tt.want {
+				t.Errorf("TokenAt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignerUsesClockAndOffset(t *testing.T) {
+	s := Signer{
+		UserNonce: "nonce",
+		UserKey:   "secret",
+		Clock:     func() time.Time { return knownTime.Add(-90 * time.Second) },
+		Offset:    90 * time.Second,
+	}
+	if got, want := s.Now(), knownTime; !got.Equal(want) {
+		t.Errorf("Now() = %v, want %v", got, want)
+	}
+	if got, want := s.Token(), "nonce:1700000000:"+knownHash; got != want {
+		t.Errorf("Token() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,307 @@
+// Package cassette records the HTTP traffic of a client to a directory and
+// replays it later without a server.
+//
+// Each exchange is stored as one JSON file, numbered in the order the
+// requests were sent, with sessions, passwords and tokens redacted. The
+// files can be read, edited or attached to a ticket as they are.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"avigilon-cli/internal/redact"
+)
+
+// Interaction is one recorded request and its response, or the error the
+// transport returned instead.
+type Interaction struct {
+	RecordedAt time.Time `json:"recordedAt"`
+	Duration   string    `json:"duration"`
+
+	Method  string  `json:"method"`
+	URL     string  `json:"url"`
+	Request Message `json:"request"`
+
+	Status   int     `json:"status,omitempty"`
+	Response Message `json:"response"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Message holds the headers and body of a request or response. JSON bodies
+// are stored as JSON, other text as a string and binary data in base64.
+type Message struct {
+	Header http.Header     `json:"header,omitempty"`
+	JSON   json.RawMessage `json:"json,omitempty"`
+	Body   string          `json:"body,omitempty"`
+	Base64 []byte          `json:"base64,omitempty"`
+}
+
+// newMessage redacts header and body into a Message.
+func newMessage(header http.Header, body []byte) Message {
+	m := Message{Header: redact.Header(header)}
+	switch {
+	case len(body) == 0:
+	case json.Valid(body):
+		if redacted, ok := redact.JSON(body); ok {
+			m.JSON = redacted
+		}
+	case utf8.Valid(body):
+		m.Body = string(body)
+	default:
+		m.Base64 = body
+	}
+	return m
+}
+
+// bytes returns the body of m.
+func (m Message) bytes() []byte {
+	switch {
+	case m.JSON != nil:
+		return m.JSON
+	case m.Base64 != nil:
+		return m.Base64
+	}
+	return []byte(m.Body)
+}
+
+// Recorder writes every exchange passing through its middleware to a
+// directory, after the ones already there.
+type Recorder struct {
+	dir string
+
+	mu   sync.Mutex
+	next int
+}
+
+// NewRecorder creates dir if needed and continues numbering after the
+// cassettes it already holds.
+func NewRecorder(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	files, err := cassetteFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{dir: dir, next: len(files) + 1}, nil
+}
+
+// Middleware records the traffic sent through next. It has the signature
+// of client.Middleware.
+func (r *Recorder) Middleware(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		reqBody, err := readBody(&req.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		it := Interaction{
+			RecordedAt: start.UTC(),
+			Duration:   time.Since(start).Round(time.Millisecond).String(),
+			Method:     req.Method,
+			URL:        redact.URL(req.URL),
+			Request:    newMessage(req.Header, reqBody),
+		}
+
+		if err != nil {
+			it.Error = err.Error()
+		} else {
+			respBody, readErr := readBody(&resp.Body)
+			if readErr != nil {
+				return nil, readErr
+			}
+			it.Status = resp.StatusCode
+			it.Response = newMessage(resp.Header, respBody)
+		}
+
+		if saveErr := r.save(it); saveErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record %s %s: %v\n", req.Method, req.URL.Path, saveErr)
+		}
+		return resp, err
+	})
+}
+
+// save writes it to the next numbered file.
+func (r *Recorder) save(it Interaction) error {
+	data, err := json.MarshalIndent(it, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	seq := r.next
+	r.next++
+	r.mu.Unlock()
+
+	u, _ := url.Parse(it.URL)
+	slug := strings.Trim(nonWord.ReplaceAllString(u.Path, "-"), "-")
+	name := fmt.Sprintf("%04d-%s-%s.json", seq, it.Method, slug)
+	return os.WriteFile(filepath.Join(r.dir, name), append(data, '\n'), 0600)
+}
+
+var nonWord = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// Player answers requests from recorded cassettes instead of a server.
+//
+// A request is answered by the first unused interaction with the same
+// method, query and path. The scheme and host are ignored, and so is a base
+// path the replaying client doesn't use (e.g. "/mt/api/rest/v1"). Query
+// parameters holding timestamps match any timestamp, so relative time
+// ranges replay on a later day. Request bodies are not compared, since
+// login tokens and continuation tokens differ between runs.
+type Player struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// Load reads the cassettes in dir.
+func Load(dir string) (*Player, error) {
+	files, err := cassetteFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no cassettes found in %s", dir)
+	}
+
+	p := &Player{}
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var it Interaction
+		if err := json.Unmarshal(raw, &it); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		p.interactions = append(p.interactions, it)
+	}
+	p.used = make([]bool, len(p.interactions))
+	return p, nil
+}
+
+// Host returns the scheme and host of the first recorded request, for
+// replaying without a saved login.
+func (p *Player) Host() string {
+	u, err := url.Parse(p.interactions[0].URL)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// Middleware serves requests from the cassettes; next is never called. It
+// has the signature of client.Middleware.
+func (p *Player) Middleware(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		it, ok := p.take(req)
+		if !ok {
+			return nil, fmt.Errorf("no recorded response left for %s %s", req.Method, redact.URL(req.URL))
+		}
+		if it.Error != "" {
+			return nil, fmt.Errorf("replayed error: %s", it.Error)
+		}
+
+		header := it.Response.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		// The recorded date would look like clock skew
+		header.Del("Date")
+
+		body := it.Response.bytes()
+		header.Del("Content-Length")
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", it.Status, http.StatusText(it.Status)),
+			StatusCode:    it.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	})
+}
+
+// take marks the interaction answering req as used and returns it.
+func (p *Player) take(req *http.Request) (Interaction, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	query := matchQuery(req.URL.Query())
+
+	for i, it := range p.interactions {
+		if p.used[i] || it.Method != req.Method {
+			continue
+		}
+		u, err := url.Parse(it.URL)
+		if err != nil || !strings.HasSuffix(u.Path, req.URL.Path) || matchQuery(u.Query()) != query {
+			continue
+		}
+		p.used[i] = true
+		return it, true
+	}
+	return Interaction{}, false
+}
+
+// matchQuery encodes query for comparison, with secrets redacted and
+// timestamps replaced by a wildcard.
+func matchQuery(query url.Values) string {
+	query = redact.Query(query)
+	for _, values := range query {
+		for i, v := range values {
+			if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				values[i] = "*"
+			}
+		}
+	}
+	return query.Encode()
+}
+
+// cassetteFiles lists the cassettes in dir in recording order.
+func cassetteFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "[0-9]*-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// readBody reads *body and replaces it with an unread copy.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	data, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
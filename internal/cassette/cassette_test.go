@@ -0,0 +1,167 @@
+package cassette
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"avigilon-cli/internal/client"
+	"avigilon-cli/pkg/models"
+)
+
+var jpeg = []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00\x01\xff\xd9")
+
+// recordingServer serves a small site below the usual base path. It
+// returns the authorization token of the last login.
+func recordingServer(t *testing.T) (*httptest.Server, *string) {
+	t.Helper()
+	var token string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		var login client.LoginPayload
+		_ = json.NewDecoder(r.Body).Decode(&login)
+		token = login.AuthorizationToken
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","result":{"session":"secret-session"}}`))
+	})
+	mux.HandleFunc("GET /cameras", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","result":{"cameras":[{"id":"cam-1","name":"Lobby"}]}}`))
+	})
+	mux.HandleFunc("GET /media", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		_, _ = w.Write(jpeg)
+	})
+	mux.HandleFunc("POST /webhooks", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "id=secret-cookie")
+		_, _ = w.Write([]byte(`{"status":"success"}`))
+	})
+	srv := httptest.NewServer(http.StripPrefix("/mt/api/rest/v1", mux))
+	t.Cleanup(srv.Close)
+	return srv, &token
+}
+
+// session runs the same requests against c, recording or replaying them.
+// at is the time of the recorded snapshot.
+func session(t *testing.T, c *client.AvigilonClient, at time.Time) ([]models.Camera, []byte) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := c.LoginContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cameras, err := c.GetCamerasContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := c.GetSnapshotWithOptionsContext(ctx, "cam-1", client.SnapshotOptions{At: at})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateWebhookContext(ctx, "https://hook.example.com", "secret-hook-token", []string{"ALL"}, false, 0); err != nil {
+		t.Fatal(err)
+	}
+	return cameras, snapshot
+}
+
+func TestRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	srv, token := recordingServer(t)
+	rec, err := NewRecorder(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := client.New(client.ClientConfig{
+		BaseURL:    srv.URL + "/mt/api/rest/v1",
+		Username:   "admin",
+		Password:   "secret-password",
+		UserNonce:  "nonce",
+		UserKey:    "secret-key",
+		Middleware: []client.Middleware{rec.Middleware},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded, _ := session(t, c, time.Now().Add(-2*time.Hour))
+
+	// Some endpoints take the session as a query parameter
+	if _, err := c.HTTP.R().SetQueryParam("session", "secret-session").Get("/media"); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := cassetteFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, file := range files {
+		names = append(names, filepath.Base(file))
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte("secret")) || bytes.Contains(data, []byte(*token)) {
+			t.Errorf("%s leaks a secret:\n%s", filepath.Base(file), data)
+		}
+	}
+	want := "0001-POST-mt-api-rest-v1-login.json 0002-GET-mt-api-rest-v1-cameras.json 0003-GET-mt-api-rest-v1-media.json 0004-POST-mt-api-rest-v1-webhooks.json 0005-GET-mt-api-rest-v1-media.json"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("cassettes %s, want %s", got, want)
+	}
+
+	// Replayed from another host, without the base path and an hour later
+	player, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host := player.Host(); host != srv.URL {
+		t.Errorf("Host() = %s, want %s", host, srv.URL)
+	}
+	srv.Close()
+	c, err = client.New(client.ClientConfig{
+		BaseURL:    "https://replay.invalid",
+		Middleware: []client.Middleware{player.Middleware},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, snapshot := session(t, c, time.Now().Add(-3*time.Hour))
+	if len(replayed) != 1 || replayed[0].ID != "cam-1" || replayed[0].Name != recorded[0].Name {
+		t.Errorf("replayed cameras %+v, want %+v", replayed, recorded)
+	}
+	if !bytes.Equal(snapshot, jpeg) {
+		t.Errorf("replayed snapshot %q, want %q", snapshot, jpeg)
+	}
+
+	// Every interaction answers once
+	if _, err := c.GetCamerasContext(context.Background()); err == nil || !strings.Contains(err.Error(), "no recorded response left") {
+		t.Errorf("GetCamerasContext() error = %v, want none left", err)
+	}
+}
+
+func TestRecorderContinues(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"0001-GET-cameras.json", "0002-GET-media.json", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec, err := NewRecorder(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.save(Interaction{Method: "GET", URL: "https://acc/mt/api/rest/v1/health"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "0003-GET-mt-api-rest-v1-health.json")); err != nil {
+		t.Error(err)
+	}
+}
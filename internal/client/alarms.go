@@ -1,52 +1,62 @@
 package client
 
 import (
-	"fmt"
+	"context"
+
+	"github.com/go-resty/resty/v2"
 	"avigilon-cli/pkg/models"
 )
 
-// GetAlarms fetches active alarms using the plural /alarms endpoint
-func (c *AvigilonClient) GetAlarms() ([]models.Alarm, error) {
+// GetAlarmsContext fetches active alarms using the plural /alarms endpoint
+func (c *AvigilonClient) GetAlarmsContext(ctx context.Context) ([]models.Alarm, error) {
 	var respData models.AlarmListResponse
 
 	// Page 3: GET /alarms (List/Search)
-	resp, err := c.HTTP.R().
-		SetResult(&respData).
-		Get("/alarms")
+	resp, err := c.execute(ctx, resty.MethodGet, "/alarms", func(req *resty.Request) {
+		req.SetResult(&respData)
+	})
 
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.IsError() {
-		return nil, fmt.Errorf("failed to get alarms: %s", resp.String())
+		return nil, newAPIError("failed to get alarms", resp)
 	}
 
 	return respData.Result.Alarms, nil
 }
 
-// UpdateAlarm performs an action on an alarm (ACKNOWLEDGE, PURGE, DISMISS)
-// Uses the singular PUT /alarm endpoint
-func (c *AvigilonClient) UpdateAlarm(sessionID, alarmID, action, note string) error {
-	payload := models.AlarmUpdatePayload{
-		Session: sessionID,
-		ID:      alarmID,
-		Action:  action,
-		Note:    note,
-	}
+// GetAlarms is GetAlarmsContext using context.Background().
+func (c *AvigilonClient) GetAlarms() ([]models.Alarm, error) {
+	return c.GetAlarmsContext(context.Background())
+}
 
+// UpdateAlarmContext performs an action on an alarm (ACKNOWLEDGE, PURGE, DISMISS)
+// Uses the singular PUT /alarm endpoint
+func (c *AvigilonClient) UpdateAlarmContext(ctx context.Context, alarmID, action, note string) error {
 	// Page 1: PUT /alarm
-	resp, err := c.HTTP.R().
-		SetBody(payload).
-		Put("/alarm")
+	resp, err := c.execute(ctx, resty.MethodPut, "/alarm", func(req *resty.Request) {
+		req.SetBody(models.AlarmUpdatePayload{
+			Session: c.Session(),
+			ID:      alarmID,
+			Action:  action,
+			Note:    note,
+		})
+	})
 
 	if err != nil {
 		return err
 	}
 
 	if resp.IsError() {
-		return fmt.Errorf("failed to update alarm: %s", resp.String())
+		return newAPIError("failed to update alarm", resp)
 	}
 
 	return nil
 }
+
+// UpdateAlarm is UpdateAlarmContext using context.Background().
+func (c *AvigilonClient) UpdateAlarm(alarmID, action, note string) error {
+	return c.UpdateAlarmContext(context.Background(), alarmID, action, note)
+}
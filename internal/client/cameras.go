@@ -1,60 +1,69 @@
 package client
 
 import (
-	"fmt"
+	"context"
+
+	"github.com/go-resty/resty/v2"
 	"avigilon-cli/pkg/models"
 )
 
-func (c *AvigilonClient) GetCameras() ([]models.Camera, error) {
+func (c *AvigilonClient) GetCamerasContext(ctx context.Context) ([]models.Camera, error) {
 	var respData models.CameraListResponse
 
-	resp, err := c.HTTP.R().
-		SetQueryParam("verbosity", "HIGH").
-		SetResult(&respData).
-		Get("/cameras")
+	resp, err := c.execute(ctx, resty.MethodGet, "/cameras", func(req *resty.Request) {
+		req.SetQueryParam("verbosity", "HIGH").
+			SetResult(&respData)
+	})
 
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.IsError() {
-		return nil, fmt.Errorf("failed to get cameras: %s", resp.String())
+		return nil, newAPIError("failed to get cameras", resp)
 	}
 
 	// Navigate the nested structure to get the slice
 	return respData.Result.Cameras, nil
 }
 
-// TriggerManualRecording starts (or stops) recording on specific cameras.
+// GetCameras is GetCamerasContext using context.Background().
+func (c *AvigilonClient) GetCameras() ([]models.Camera, error) {
+	return c.GetCamerasContext(context.Background())
+}
+
+// TriggerManualRecordingContext starts (or stops) recording on specific cameras.
 // action: "START" or "STOP"
 // duration: Seconds to record (ignored if action is STOP)
-// TriggerManualRecording starts (or stops) recording on specific cameras.
-func (c *AvigilonClient) TriggerManualRecording(sessionID string, cameraIDs []string, action string, duration int) error {
+func (c *AvigilonClient) TriggerManualRecordingContext(ctx context.Context, cameraIDs []string, action string, duration int) error {
 	// Logic to handle API requirement: maxDurationSec must NOT be present for STOP
 	var durationPtr *int
 	if action == "START" {
 		durationPtr = &duration
 	}
 
-	payload := models.ManualRecordingPayload{
-		Session:        sessionID,
-		CameraIDs:      cameraIDs,
-		Action:         action,
-		MaxDurationSec: durationPtr, // Will be nil if STOP, omitting the field
-	}
-
 	// Page 31: POST /camera/record/manual
-	resp, err := c.HTTP.R().
-		SetBody(payload).
-		Post("/camera/record/manual")
+	resp, err := c.execute(ctx, resty.MethodPost, "/camera/record/manual", func(req *resty.Request) {
+		req.SetBody(models.ManualRecordingPayload{
+			Session:        c.Session(),
+			CameraIDs:      cameraIDs,
+			Action:         action,
+			MaxDurationSec: durationPtr, // Will be nil if STOP, omitting the field
+		})
+	})
 
 	if err != nil {
 		return err
 	}
 
 	if resp.IsError() {
-		return fmt.Errorf("failed to trigger recording: %s", resp.String())
+		return newAPIError("failed to trigger recording", resp)
 	}
 
 	return nil
 }
+
+// TriggerManualRecording is TriggerManualRecordingContext using context.Background().
+func (c *AvigilonClient) TriggerManualRecording(cameraIDs []string, action string, duration int) error {
+	return c.TriggerManualRecordingContext(context.Background(), cameraIDs, action, duration)
+}
@@ -1,9 +1,13 @@
 package client
 
 import (
-	"crypto/tls"
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 	"avigilon-cli/internal/auth"
@@ -12,6 +16,39 @@ import (
 type AvigilonClient struct {
 	HTTP   *resty.Client
 	Config ClientConfig
+
+	// OnFingerprintLearned is called when a server certificate is trusted
+	// on first use, so the caller can pin it for future connections.
+	OnFingerprintLearned func(fingerprint string)
+
+	// OnSessionRenewed is called with the new session ID whenever the client
+	// transparently logs in again after the previous session expired.
+	OnSessionRenewed func(sessionID string)
+
+	// OnSessionUsed is called after every request the server accepted with
+	// the current session.
+	OnSessionUsed func()
+
+	// LoadCredentials, if set, fills in the login credentials of the copy
+	// of Config it is given the first time the client has to log in again.
+	// It lets callers keep secrets locked (e.g. in an encrypted store) until
+	// a session actually expires. A failed load is tried again on the next
+	// re-login.
+	LoadCredentials func(cfg *ClientConfig) error
+
+	// mu guards the session and, once loaded, the login credentials of
+	// Config, which a re-login may fill in while other requests run.
+	mu          sync.RWMutex
+	session     string
+	credsLoaded bool
+	reloginMu   sync.Mutex
+
+	skewMu    sync.RWMutex
+	skew      time.Duration
+	skewKnown bool
+
+	metadataLimit *limiter
+	mediaLimit    *limiter
 }
 
 type ClientConfig struct {
@@ -21,8 +58,47 @@ type ClientConfig struct {
 	UserNonce     string // For Auth Token
 	UserKey       string // For Auth Token
 	IntegrationID string // For Auth Token
+
+	TLS TLSConfig
+
+	// Timeout bounds each HTTP request. Zero means no limit beyond the
+	// deadline of the request's context.
+	Timeout time.Duration
+
+	// CompensateSkew signs login tokens with the server's time, derived from
+	// the Date header of its responses, instead of the local clock.
+	CompensateSkew bool
+
+	// Retry retries requests failing with a transient error. The zero
+	// value never retries.
+	Retry RetryPolicy
+
+	// Limits caps the rate and concurrency of requests. The zero value
+	// is unlimited.
+	Limits Limits
+
+	// Clock is the local clock, time.Now if nil.
+	Clock auth.Clock
+
+	// UserAgent is sent with every request, DefaultUserAgent if empty.
+	UserAgent string
+
+	// Middleware wraps the HTTP transport, first entry outermost. It sees
+	// every request, including logins and re-logins.
+	Middleware []Middleware
+
+	// Logger receives a trace of every request, with secrets redacted:
+	// method, path, status, latency and sizes at info level, the query and
+	// headers at debug level. Nil disables tracing.
+	Logger *slog.Logger
 }
 
+// DefaultUserAgent identifies the client to the Web Endpoint.
+const DefaultUserAgent = "avigilon-cli"
+
+// Middleware decorates an http.RoundTripper, e.g. to log or record traffic.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
 // LoginPayload matches the JSON body required by POST /login (Page 40)
 type LoginPayload struct {
 	Username           string `json:"username"`
@@ -38,52 +114,163 @@ type LoginResponse struct {
 	} `json:"result"`
 }
 
-func New(cfg ClientConfig) *AvigilonClient {
+// New creates a client for the given configuration. It fails if the TLS
+// material referenced by cfg.TLS cannot be loaded.
+func New(cfg ClientConfig) (*AvigilonClient, error) {
+	c := &AvigilonClient{
+		Config:        cfg,
+		metadataLimit: newLimiter(classMetadata, cfg.Limits.Metadata),
+		mediaLimit:    newLimiter(classMedia, cfg.Limits.Media),
+	}
+
+	tlsConfig, err := buildTLSConfig(cfg.TLS, func(fingerprint string) {
+		if c.OnFingerprintLearned != nil {
+			c.OnFingerprintLearned(fingerprint)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Self-signed certs are common on on-prem VMS, see TLSConfig for the
+	// pinning, trust-on-first-use and insecure options.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	var rt http.RoundTripper = transport
+	for i := len(cfg.Middleware) - 1; i >= 0; i-- {
+		rt = cfg.Middleware[i](rt)
+	}
+	if cfg.Logger != nil {
+		rt = traceTransport{next: rt, logger: cfg.Logger}
+	}
+
 	r := resty.New()
+	r.SetTransport(rt)
 	r.SetBaseURL(cfg.BaseURL)
 	
 	// PDF Page 1: "Response Content Type: application/json"
 	r.SetHeader("Content-Type", "application/json")
 	r.SetHeader("Accept", "application/json")
 
-	// Disable TLS verification for testing (common in on-prem VMS with self-signed certs)
-	r.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	userAgent := cfg.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	r.SetHeader("User-Agent", userAgent)
+
+	if cfg.Timeout > 0 {
+		r.SetTimeout(cfg.Timeout)
+	}
+
+	// Every response with a Date header keeps the clock skew up to date
+	r.OnAfterResponse(c.recordClockSkew)
+
+	c.HTTP = r
+	return c, nil
+}
+
+// Session returns the session ID currently used for requests.
+func (c *AvigilonClient) Session() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.session
+}
+
+// SetSession sets the session ID sent with every subsequent request,
+// e.g. one restored from the config file.
+func (c *AvigilonClient) SetSession(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.session = sessionID
+}
 
-	return &AvigilonClient{
-		HTTP:   r,
-		Config: cfg,
+// CanRelogin reports whether the client holds, or can load, enough
+// credentials to obtain a new session on its own.
+func (c *AvigilonClient) CanRelogin() bool {
+	if c.LoadCredentials != nil {
+		return true
 	}
+	creds := c.credentials()
+	return creds.Password != "" && creds.UserNonce != "" && creds.UserKey != ""
 }
 
-// Login authenticates with the VMS, sets the session header internally, 
+// credentials returns a copy of Config that is safe to read while a
+// re-login loads the credentials.
+func (c *AvigilonClient) credentials() ClientConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Config
+}
+
+// EnsureCredentials runs LoadCredentials unless it already succeeded, and
+// stores the loaded credentials for the following logins.
+func (c *AvigilonClient) EnsureCredentials() error {
+	if c.LoadCredentials == nil {
+		return nil
+	}
+	c.mu.RLock()
+	loaded := c.credsLoaded
+	c.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	cfg := c.credentials()
+	if err := c.LoadCredentials(&cfg); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Config.Username = cfg.Username
+	c.Config.Password = cfg.Password
+	c.Config.UserNonce = cfg.UserNonce
+	c.Config.UserKey = cfg.UserKey
+	c.Config.IntegrationID = cfg.IntegrationID
+	c.credsLoaded = true
+	return nil
+}
+
+// LoginContext authenticates with the VMS, sets the session header internally, 
 // and returns the session ID string for persistence.
-func (c *AvigilonClient) Login() (string, error) {
+func (c *AvigilonClient) LoginContext(ctx context.Context) (string, error) {
 	// 1. Generate the cryptographic signature
-	authToken := auth.GenerateAuthToken(
-		c.Config.UserNonce,
-		c.Config.UserKey,
-		c.Config.IntegrationID,
-	)
+	creds := c.credentials()
+	signer := auth.Signer{
+		UserNonce:     creds.UserNonce,
+		UserKey:       creds.UserKey,
+		IntegrationID: creds.IntegrationID,
+		Clock:         c.Now,
+		Offset:        c.signingOffset(ctx),
+	}
+	authToken := signer.Token()
 
 	payload := LoginPayload{
-		Username:           c.Config.Username,
-		Password:           c.Config.Password,
+		Username:           creds.Username,
+		Password:           creds.Password,
 		ClientName:         "Avigilon-Go-CLI",
 		AuthorizationToken: authToken,
 	}
 
 	// 2. Make Request
+	release, err := c.acquire(ctx, "/login")
+	if err != nil {
+		return "", err
+	}
 	resp, err := c.HTTP.R().
+		SetContext(ctx).
 		SetBody(payload).
 		SetResult(&LoginResponse{}).
 		Post("/login")
+	release()
 
 	if err != nil {
 		return "", err
 	}
 
 	if resp.IsError() {
-		return "", fmt.Errorf("login failed: %s", resp.String())
+		return "", c.skewHint(newAPIError("login failed", resp))
 	}
 
 	// 3. Extract Session
@@ -100,17 +287,159 @@ func (c *AvigilonClient) Login() (string, error) {
 
 	// 4. Inject Session into all future requests for this client instance
 	// Page 1: "x-avg-session: The session parameter returned by the login request (header)"
-	c.HTTP.SetHeader("x-avg-session", sessionID)
+	c.SetSession(sessionID)
 
 	// Return the session ID so it can be saved to config
 	return sessionID, nil
 }
 
-// GetHealth checks the node status
-func (c *AvigilonClient) GetHealth() (string, error) {
-	resp, err := c.HTTP.R().Get("/health")
+// Login is LoginContext using context.Background().
+func (c *AvigilonClient) Login() (string, error) {
+	return c.LoginContext(context.Background())
+}
+
+// newRequest creates a request bound to ctx and carrying the current session header.
+func (c *AvigilonClient) newRequest(ctx context.Context) *resty.Request {
+	req := c.HTTP.R().SetContext(ctx)
+	if session := c.Session(); session != "" {
+		req.SetHeader("x-avg-session", session)
+	}
+	return req
+}
+
+// execute sends the request produced by build, retrying transient failures
+// as configured by Config.Retry. If the server rejects the session and the
+// client holds credentials, it logs in again and replays the request once.
+// build runs again for the replay, so payloads that embed the session pick
+// up the new one.
+func (c *AvigilonClient) execute(ctx context.Context, method, path string, build func(req *resty.Request)) (*resty.Response, error) {
+	stale := c.Session()
+
+	resp, err := c.send(ctx, method, path, build)
+	if err != nil || !isAuthFailure(resp) || !c.CanRelogin() {
+		c.sessionUsed(resp, err)
+		return resp, err
+	}
+
+	if err := c.renewSession(ctx, stale); err != nil {
+		return nil, fmt.Errorf("session expired and re-login failed: %w", err)
+	}
+
+	resp, err = c.send(ctx, method, path, build)
+	c.sessionUsed(resp, err)
+	return resp, err
+}
+
+// sessionUsed reports a request the server accepted to OnSessionUsed.
+func (c *AvigilonClient) sessionUsed(resp *resty.Response, err error) {
+	if c.OnSessionUsed != nil && err == nil && resp.IsSuccess() && c.Session() != "" {
+		c.OnSessionUsed()
+	}
+}
+
+// renewSession logs in again unless another request already replaced the
+// stale session in the meantime.
+func (c *AvigilonClient) renewSession(ctx context.Context, stale string) error {
+	c.reloginMu.Lock()
+	defer c.reloginMu.Unlock()
+
+	if c.Session() != stale {
+		return nil
+	}
+
+	if err := c.EnsureCredentials(); err != nil {
+		return fmt.Errorf("failed to load credentials: %w", err)
+	}
+
+	sessionID, err := c.LoginContext(ctx)
+	if err != nil {
+		return err
+	}
+	if c.OnSessionRenewed != nil {
+		c.OnSessionRenewed(sessionID)
+	}
+	return nil
+}
+
+// codeSessionInvalid is the error code of a 403 for an unknown or expired
+// session, as opposed to one the user lacks the permission for.
+const codeSessionInvalid = "SESSION_INVALID"
+
+// isAuthFailure reports whether the server rejected the session itself, so
+// that logging in again may help.
+func isAuthFailure(resp *resty.Response) bool {
+	switch resp.StatusCode() {
+	case http.StatusUnauthorized:
+		return true
+	case http.StatusForbidden:
+		return newAPIError("", resp).Code == codeSessionInvalid
+	}
+	return false
+}
+
+// LogoutPayload matches the JSON body of POST /logout
+type LogoutPayload struct {
+	Session string `json:"session"`
+}
+
+// LogoutSessionContext ends sessionID on the server. It never logs in again:
+// a session the server rejects is already gone and yields ErrUnauthorized.
+func (c *AvigilonClient) LogoutSessionContext(ctx context.Context, sessionID string) error {
+	release, err := c.acquire(ctx, "/logout")
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTP.R().
+		SetContext(ctx).
+		SetHeader("x-avg-session", sessionID).
+		SetBody(LogoutPayload{Session: sessionID}).
+		Post("/logout")
+	release()
+
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return newAPIError("logout failed", resp)
+	}
+
+	return nil
+}
+
+// LogoutContext ends the current session and forgets it, so later requests
+// go out without a session until the next login.
+func (c *AvigilonClient) LogoutContext(ctx context.Context) error {
+	session := c.Session()
+	if session == "" {
+		return nil
+	}
+
+	if err := c.LogoutSessionContext(ctx, session); err != nil {
+		return err
+	}
+	c.SetSession("")
+	return nil
+}
+
+// Logout is LogoutContext using context.Background().
+func (c *AvigilonClient) Logout() error {
+	return c.LogoutContext(context.Background())
+}
+
+// GetHealthContext checks the node status
+func (c *AvigilonClient) GetHealthContext(ctx context.Context) (string, error) {
+	resp, err := c.execute(ctx, resty.MethodGet, "/health", func(req *resty.Request) {})
 	if err != nil {
 		return "", err
 	}
+	if resp.IsError() {
+		return "", newAPIError("failed to get health", resp)
+	}
 	return resp.String(), nil
 }
+
+// GetHealth is GetHealthContext using context.Background().
+func (c *AvigilonClient) GetHealth() (string, error) {
+	return c.GetHealthContext(context.Background())
+}
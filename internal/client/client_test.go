@@ -0,0 +1,138 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// sessionServer accepts the session "s1" until the first request that
+// carries it, then rejects it with status and code, as a server that
+// restarted or revoked it would. Logins hand out "s2".
+func sessionServer(t *testing.T, status int, code string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var logins atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/login":
+			logins.Add(1)
+			_, _ = w.Write([]byte(`{"status":"success","result":{"session":"s2"}}`))
+		case r.Header.Get("x-avg-session") == "s2":
+			_, _ = w.Write([]byte(`{"status":"success","result":{"status":"GOOD"}}`))
+		default:
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"status":"error","code":"` + code + `"}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &logins
+}
+
+func TestReloginOnlyForInvalidSession(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		code    string
+		relogin bool
+	}{
+		{"unauthorized", http.StatusUnauthorized, "SESSION_INVALID", true},
+		{"forbidden, session invalid", http.StatusForbidden, "SESSION_INVALID", true},
+		{"forbidden, no permission", http.StatusForbidden, "PERMISSION_DENIED", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, logins := sessionServer(t, tt.status, tt.code)
+			c, err := New(ClientConfig{BaseURL: srv.URL, Password: "p", UserNonce: "n", UserKey: "k"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetSession("s1")
+
+			_, err = c.GetHealthContext(context.Background())
+			if tt.relogin {
+				if err != nil {
+					t.Fatalf("GetHealthContext() error = %v, want the request replayed", err)
+				}
+				if logins.Load() != 1 || c.Session() != "s2" {
+					t.Errorf("%d logins, session %q; want 1 and s2", logins.Load(), c.Session())
+				}
+				return
+			}
+			if !errors.Is(err, ErrUnauthorized) {
+				t.Errorf("GetHealthContext() error = %v, want ErrUnauthorized", err)
+			}
+			if logins.Load() != 0 {
+				t.Errorf("%d logins, want none", logins.Load())
+			}
+		})
+	}
+}
+
+func TestLoadCredentialsRetriedAfterFailure(t *testing.T) {
+	srv, logins := sessionServer(t, http.StatusUnauthorized, "SESSION_INVALID")
+	c, err := New(ClientConfig{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetSession("s1")
+
+	var loads int
+	c.LoadCredentials = func(cfg *ClientConfig) error {
+		loads++
+		if loads == 1 {
+			return errors.New("store locked")
+		}
+		cfg.Password, cfg.UserNonce, cfg.UserKey = "p", "n", "k"
+		return nil
+	}
+
+	ctx := context.Background()
+	if _, err := c.GetHealthContext(ctx); err == nil {
+		t.Fatal("GetHealthContext() succeeded with a locked store")
+	}
+	if _, err := c.GetHealthContext(ctx); err != nil {
+		t.Fatalf("GetHealthContext() error = %v, want the second load to succeed", err)
+	}
+	if loads != 2 || logins.Load() != 1 {
+		t.Errorf("%d loads and %d logins, want 2 and 1", loads, logins.Load())
+	}
+
+	// Loaded credentials are kept for later logins
+	if err := c.EnsureCredentials(); err != nil || loads != 2 {
+		t.Errorf("EnsureCredentials() = %v after %d loads, want no further load", err, loads)
+	}
+}
+
+func TestConcurrentRelogin(t *testing.T) {
+	srv, logins := sessionServer(t, http.StatusUnauthorized, "SESSION_INVALID")
+	c, err := New(ClientConfig{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetSession("s1")
+	c.LoadCredentials = func(cfg *ClientConfig) error {
+		cfg.Password, cfg.UserNonce, cfg.UserKey = "p", "n", "k"
+		return nil
+	}
+
+	// Requests sharing the expired session log in once; run with -race
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetHealthContext(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if logins.Load() != 1 {
+		t.Errorf("%d logins, want 1", logins.Load())
+	}
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// MaxClockSkew is the clock difference beyond which the VMS rejects login
+// signatures with 403 Forbidden.
+const MaxClockSkew = 5 * time.Minute
+
+// ErrNoServerDate is returned when the server did not send a Date header.
+var ErrNoServerDate = errors.New("server response has no Date header")
+
+// Now returns the local time from Config.Clock, or time.Now.
+func (c *AvigilonClient) Now() time.Time {
+	if c.Config.Clock != nil {
+		return c.Config.Clock()
+	}
+	return time.Now()
+}
+
+// ClockSkew returns how far the server clock is ahead of the local one
+// (negative if it is behind), as seen in the last response that carried a
+// Date header. ok is false until such a response was received.
+func (c *AvigilonClient) ClockSkew() (skew time.Duration, ok bool) {
+	c.skewMu.RLock()
+	defer c.skewMu.RUnlock()
+	return c.skew, c.skewKnown
+}
+
+// recordClockSkew is a resty response hook that updates the skew from the
+// Date header of every response.
+func (c *AvigilonClient) recordClockSkew(_ *resty.Client, resp *resty.Response) error {
+	if skew, err := c.skewFrom(resp.Header()); err == nil {
+		c.skewMu.Lock()
+		c.skew, c.skewKnown = skew, true
+		c.skewMu.Unlock()
+	}
+	return nil
+}
+
+// skewFrom compares the Date header with the local clock. The header only
+// has second precision, so the server time is taken as the middle of that
+// second and the result is rounded to whole seconds.
+func (c *AvigilonClient) skewFrom(h http.Header) (time.Duration, error) {
+	raw := h.Get("Date")
+	if raw == "" {
+		return 0, ErrNoServerDate
+	}
+	serverTime, err := http.ParseTime(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Date header %q: %w", raw, err)
+	}
+	return serverTime.Add(500 * time.Millisecond).Sub(c.Now()).Round(time.Second), nil
+}
+
+// MeasureClockSkewContext requests /health, which needs no session, and
+// returns the skew derived from its Date header. The result is also
+// available from ClockSkew afterwards.
+func (c *AvigilonClient) MeasureClockSkewContext(ctx context.Context) (time.Duration, error) {
+	release, err := c.acquire(ctx, "/health")
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.HTTP.R().SetContext(ctx).Get("/health")
+	release()
+	if err != nil {
+		return 0, err
+	}
+	return c.skewFrom(resp.Header())
+}
+
+// MeasureClockSkew is MeasureClockSkewContext using context.Background().
+func (c *AvigilonClient) MeasureClockSkew() (time.Duration, error) {
+	return c.MeasureClockSkewContext(context.Background())
+}
+
+// signingOffset returns the correction applied to login timestamps: the
+// measured skew when Config.CompensateSkew is set, measuring it first if no
+// response has been seen yet.
+func (c *AvigilonClient) signingOffset(ctx context.Context) time.Duration {
+	if !c.Config.CompensateSkew {
+		return 0
+	}
+	if skew, ok := c.ClockSkew(); ok {
+		return skew
+	}
+	skew, _ := c.MeasureClockSkewContext(ctx)
+	return skew
+}
+
+// skewHint explains a rejected login when the clocks are too far apart.
+func (c *AvigilonClient) skewHint(err error) error {
+	skew, ok := c.ClockSkew()
+	if !ok || c.Config.CompensateSkew || !errors.Is(err, ErrUnauthorized) {
+		return err
+	}
+	if skew < MaxClockSkew && skew > -MaxClockSkew {
+		return err
+	}
+	return fmt.Errorf("%w (the server clock differs from the local clock by %s; sync the clock or enable skew compensation)", err, skew)
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serverTime is the clock of the test server, 10 minutes ahead of the
+// local clock used by the clients below.
+var serverTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// loginServer answers /health and /login with serverTime in the Date header
+// and reports the timestamp of every login token it receives.
+func loginServer(t *testing.T) (*httptest.Server, <-chan int64) {
+	t.Helper()
+	stamps := make(chan int64, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", serverTime.Format(http.TimeFormat))
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/login" {
+			_, _ = w.Write([]byte(`{"status":"success","result":{"status":"GOOD"}}`))
+			return
+		}
+
+		var p LoginPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("decoding login payload: %v", err)
+		}
+		parts := strings.Split(p.AuthorizationToken, ":")
+		ts, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			t.Errorf("token %q has no timestamp", p.AuthorizationToken)
+		}
+		stamps <- ts
+		_, _ = w.Write([]byte(`{"status":"success","result":{"session":"s1"}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, stamps
+}
+
+func TestCompensateSkewSignsWithServerTime(t *testing.T) {
+	// The Date header has second precision and is read as the middle of
+	// its second, so the local clock sits half a second into one
+	local := serverTime.Add(-10*time.Minute + 500*time.Millisecond)
+
+	tests := []struct {
+		name       string
+		compensate bool
+		want       time.Time
+	}{
+		{"compensated", true, serverTime},
+		{"local clock", false, local},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, stamps := loginServer(t)
+			c, err := New(ClientConfig{
+				BaseURL:        srv.URL,
+				UserNonce:      "nonce",
+				UserKey:        "key",
+				Password:       "password",
+				CompensateSkew: tt.compensate,
+				Clock:          func() time.Time { return local },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := c.LoginContext(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := <-stamps; got != tt.want.Unix() {
+				t.Errorf("token signed at %s, want %s", time.Unix(got, 0).UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestClockSkewFromDateHeader(t *testing.T) {
+	srv, _ := loginServer(t)
+	c, err := New(ClientConfig{
+		BaseURL: srv.URL,
+		Clock:   func() time.Time { return serverTime.Add(-10*time.Minute + 500*time.Millisecond) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	skew, err := c.MeasureClockSkewContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skew != 10*time.Minute {
+		t.Errorf("MeasureClockSkewContext() = %s, want 10m0s", skew)
+	}
+	if got, ok := c.ClockSkew(); !ok || got != skew {
+		t.Errorf("ClockSkew() = %s, %v; want %s, true", got, ok, skew)
+	}
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Sentinel errors matched by APIError via errors.Is.
+var (
+	ErrUnauthorized      = errors.New("unauthorized")
+	ErrNotFound          = errors.New("not found")
+	ErrRateLimited       = errors.New("rate limited")
+	ErrServerUnavailable = errors.New("server unavailable")
+)
+
+// APIError describes a non-2xx response from the Web Endpoint Service.
+type APIError struct {
+	Op         string // What the client was doing, e.g. "failed to get cameras"
+	Method     string
+	Endpoint   string
+	StatusCode int
+	Code       string // Avigilon error code from the JSON body, if any
+	Message    string // Avigilon error message, or the raw body if it wasn't JSON
+	RequestID  string
+}
+
+// apiErrorBody covers the field names seen in WEP error responses.
+type apiErrorBody struct {
+	Status       string          `json:"status"`
+	Code         json.RawMessage `json:"code"`
+	ErrorCode    json.RawMessage `json:"errorCode"`
+	ErrorType    string          `json:"errorType"`
+	Message      string          `json:"message"`
+	ErrorMessage string          `json:"errorMessage"`
+	RequestID    string          `json:"requestId"`
+}
+
+// newAPIError builds an APIError from a failed response.
+func newAPIError(op string, resp *resty.Response) *APIError {
+	e := &APIError{
+		Op:         op,
+		StatusCode: resp.StatusCode(),
+		RequestID:  resp.Header().Get("X-Request-Id"),
+	}
+
+	if req := resp.Request; req != nil {
+		e.Method = req.Method
+		if req.RawRequest != nil {
+			e.Endpoint = req.RawRequest.URL.Path
+		}
+	}
+
+	var body apiErrorBody
+	if err := json.Unmarshal(resp.Body(), &body); err == nil {
+		e.Code = firstNonEmpty(rawString(body.Code), rawString(body.ErrorCode), body.ErrorType)
+		e.Message = firstNonEmpty(body.Message, body.ErrorMessage)
+		if e.RequestID == "" {
+			e.RequestID = body.RequestID
+		}
+	} else {
+		e.Message = strings.TrimSpace(resp.String())
+	}
+
+	return e
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s: %d %s", e.Op, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Code != "" {
+		fmt.Fprintf(&b, " [%s]", e.Code)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	if e.Endpoint != "" {
+		fmt.Fprintf(&b, " (%s %s", e.Method, e.Endpoint)
+		if e.RequestID != "" {
+			fmt.Fprintf(&b, ", request %s", e.RequestID)
+		}
+		b.WriteString(")")
+	}
+
+	return b.String()
+}
+
+// Is maps the HTTP status onto the sentinel errors.
+// The WEP answers both 401 and 403 for missing or expired sessions.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServerUnavailable:
+		return e.StatusCode == http.StatusBadGateway ||
+			e.StatusCode == http.StatusServiceUnavailable ||
+			e.StatusCode == http.StatusGatewayTimeout
+	}
+	return false
+}
+
+// rawString renders a JSON code that may be either a string or a number.
+func rawString(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
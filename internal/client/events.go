@@ -1,50 +1,294 @@
 package client
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/go-resty/resty/v2"
 	"avigilon-cli/pkg/models"
 )
 
 // Avigilon strict ISO 8601 format with milliseconds and Z suffix
 const AvigilonTimeFormat = "2006-01-02T15:04:05.000Z"
 
-// GetEvents searches for events on a specific server within a time range.
-func (c *AvigilonClient) GetEvents(serverID string, from time.Time, to time.Time, topics []string) ([]models.Event, error) {
-	var respData models.EventListResponse
+// DefaultEventPageSize is the number of events requested per page.
+const DefaultEventPageSize = 100
+
+// EventQuery describes an event search on a single server.
+type EventQuery struct {
+	ServerID string
+	From     time.Time
+	To       time.Time // Zero means "until now"
+	Topics   []string
+	PageSize int // Events per request, DefaultEventPageSize if zero
+	Limit    int // Maximum events in total, zero for no limit
+}
+
+// EventIterator pages through an event search by following the
+// continuation token returned with each page.
+type EventIterator struct {
+	c     *AvigilonClient
+	query EventQuery
 
-	req := c.HTTP.R().
-		SetQueryParam("queryType", "TIME_RANGE").
-		SetQueryParam("serverId", serverID).
-		SetQueryParam("from", from.UTC().Format(AvigilonTimeFormat))
+	token   string
+	started bool
+	done    bool
+	atLimit bool // Limit reached with a token left; the next call probes it
+	count   int
+	capped  bool
+}
 
-	if !to.IsZero() {
-		req.SetQueryParam("to", to.UTC().Format(AvigilonTimeFormat))
+// SearchEvents returns an iterator over the events matching q.
+// No request is made until Next is called.
+func (c *AvigilonClient) SearchEvents(q EventQuery) *EventIterator {
+	if q.PageSize <= 0 {
+		q.PageSize = DefaultEventPageSize
 	}
+	return &EventIterator{c: c, query: q}
+}
 
-	// FIX: Use req.QueryParam.Add() to append multiple values for the same key.
-	// SetQueryParam() overwrites, which is why only the last topic was working previously.
-	for _, t := range topics {
-		if t != "" {
-			req.QueryParam.Add("eventTopics", t)
+// Done reports whether the search is exhausted or reached its limit.
+func (it *EventIterator) Done() bool {
+	return it.done
+}
+
+// Capped reports whether the search stopped at the limit while the
+// server still had more events. A continuation token alone doesn't count:
+// once the limit is reached, the call to Next that finishes the search
+// fetches one more event to tell.
+func (it *EventIterator) Capped() bool {
+	return it.capped
+}
+
+// Next fetches the next page of events. Once Done reports true, Next
+// returns no events and no error.
+func (it *EventIterator) Next(ctx context.Context) ([]models.Event, error) {
+	if it.done {
+		return nil, nil
+	}
+	if it.atLimit {
+		more, _, err := it.c.continueEventsPage(ctx, it.query.ServerID, it.token, 1)
+		if err != nil {
+			return nil, err
 		}
+		it.capped = len(more) > 0
+		it.done = true
+		return nil, nil
 	}
 
-	// Default limit
-	req.SetQueryParam("limit", "100")
-
-	resp, err := req.
-		SetResult(&respData).
-		Get("/events/search")
+	pageSize := it.query.PageSize
+	if it.query.Limit > 0 && it.query.Limit-it.count < pageSize {
+		pageSize = it.query.Limit - it.count
+	}
 
+	var events []models.Event
+	var err error
+	if !it.started {
+		events, it.token, err = it.c.searchEventsPage(ctx, it.query, pageSize)
+		it.started = true
+	} else {
+		events, it.token, err = it.c.continueEventsPage(ctx, it.query.ServerID, it.token, pageSize)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	// Guard against servers that ignore the requested limit
+	if it.query.Limit > 0 && it.count+len(events) > it.query.Limit {
+		events = events[:it.query.Limit-it.count]
+		it.capped = true
+	}
+	it.count += len(events)
+
+	switch {
+	case it.token == "" || len(events) == 0:
+		it.done = true
+	case it.capped:
+		it.done = true
+	case it.query.Limit > 0 && it.count >= it.query.Limit:
+		it.atLimit = true
+	}
+
+	return events, nil
+}
+
+// All drains the iterator and returns every remaining event.
+func (it *EventIterator) All(ctx context.Context) ([]models.Event, error) {
+	var all []models.Event
+	for !it.Done() {
+		events, err := it.Next(ctx)
+		if err != nil {
+			return all, err
+		}
+		all = append(all, events...)
+	}
+	return all, nil
+}
+
+// searchEventsPage starts a TIME_RANGE search and returns the first page
+// together with the continuation token.
+func (c *AvigilonClient) searchEventsPage(ctx context.Context, q EventQuery, pageSize int) ([]models.Event, string, error) {
+	var respData models.EventListResponse
+
+	resp, err := c.execute(ctx, resty.MethodGet, "/events/search", func(req *resty.Request) {
+		req.SetQueryParam("queryType", "TIME_RANGE").
+			SetQueryParam("serverId", q.ServerID).
+			SetQueryParam("from", q.From.UTC().Format(AvigilonTimeFormat))
+
+		if !q.To.IsZero() {
+			req.SetQueryParam("to", q.To.UTC().Format(AvigilonTimeFormat))
+		}
+
+		// FIX: Use req.QueryParam.Add() to append multiple values for the same key.
+		// SetQueryParam() overwrites, which is why only the last topic was working previously.
+		for _, t := range q.Topics {
+			if t != "" {
+				req.QueryParam.Add("eventTopics", t)
+			}
+		}
+
+		req.SetQueryParam("limit", strconv.Itoa(pageSize))
+
+		req.SetResult(&respData)
+	})
+
+	if err != nil {
+		return nil, "", err
+	}
+
 	if resp.IsError() {
-		return nil, fmt.Errorf("failed to search events on server %s: %s", serverID, resp.String())
+		return nil, "", newAPIError(fmt.Sprintf("failed to search events on server %s", q.ServerID), resp)
 	}
 
-	return respData.Result.Events, nil
+	return respData.Result.Events, respData.Result.Token, nil
+}
+
+// continueEventsPage fetches the page following token (queryType CONTINUE).
+func (c *AvigilonClient) continueEventsPage(ctx context.Context, serverID, token string, pageSize int) ([]models.Event, string, error) {
+	var respData models.EventListResponse
+
+	resp, err := c.execute(ctx, resty.MethodGet, "/events/search", func(req *resty.Request) {
+		req.SetQueryParam("queryType", "CONTINUE").
+			SetQueryParam("token", token).
+			SetQueryParam("limit", strconv.Itoa(pageSize)).
+			SetResult(&respData)
+	})
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	if resp.IsError() {
+		return nil, "", newAPIError(fmt.Sprintf("failed to continue event search on server %s", serverID), resp)
+	}
+
+	return respData.Result.Events, respData.Result.Token, nil
+}
+
+// GetEventsContext searches for events on a specific server within a time range,
+// following continuation tokens until every matching event has been fetched.
+func (c *AvigilonClient) GetEventsContext(ctx context.Context, serverID string, from time.Time, to time.Time, topics []string) ([]models.Event, error) {
+	return c.SearchEvents(EventQuery{
+		ServerID: serverID,
+		From:     from,
+		To:       to,
+		Topics:   topics,
+	}).All(ctx)
+}
+
+// GetEvents is GetEventsContext using context.Background().
+func (c *AvigilonClient) GetEvents(serverID string, from time.Time, to time.Time, topics []string) ([]models.Event, error) {
+	return c.GetEventsContext(context.Background(), serverID, from, to, topics)
+}
+
+// ServerSearchResult summarizes the event search on one server.
+type ServerSearchResult struct {
+	ServerID   string `json:"serverId"`
+	ServerName string `json:"serverName"`
+	Events     int    `json:"events"`
+	Capped     bool   `json:"capped,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	Err error `json:"-"`
+}
+
+// SearchEventsOnServers runs q on every server with at most concurrency
+// searches in flight. The events are merged and sorted by timestamp; events
+// with equal timestamps keep the order of servers, then the server's own order.
+// q.Limit applies to each server and to the merged result. Per-server failures
+// are reported in the results rather than failing the whole search.
+func (c *AvigilonClient) SearchEventsOnServers(ctx context.Context, servers []models.Server, q EventQuery, concurrency int) ([]models.Event, []ServerSearchResult) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := make([]ServerSearchResult, len(servers))
+	perServer := make([][]models.Event, len(servers))
+
+	// 1. Bounded worker pool, one job per server
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency && w < len(servers); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				query := q
+				query.ServerID = servers[i].ID
+
+				it := c.SearchEvents(query)
+				events, err := it.All(ctx)
+
+				perServer[i] = events
+				results[i] = ServerSearchResult{
+					ServerID:   servers[i].ID,
+					ServerName: servers[i].Name,
+					Events:     len(events),
+					Capped:     it.Capped(),
+					Err:        err,
+				}
+				if err != nil {
+					results[i].Error = err.Error()
+				}
+			}
+		}()
+	}
+	for i := range servers {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return MergeEvents(perServer, q.Limit), results
+}
+
+// MergeEvents merges the events of several servers and sorts them by
+// timestamp; events with equal timestamps keep the order of servers, then
+// the server's own order. At most limit events are returned, all if zero.
+func MergeEvents(perServer [][]models.Event, limit int) []models.Event {
+	var merged []models.Event
+	for _, events := range perServer {
+		merged = append(merged, events...)
+	}
+	stamps := make([]time.Time, len(merged))
+	order := make([]int, len(merged))
+	for i := range merged {
+		stamps[i], _ = time.Parse(time.RFC3339, merged[i].Timestamp)
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return stamps[order[a]].Before(stamps[order[b]]) })
+
+	sorted := make([]models.Event, len(merged))
+	for i, idx := range order {
+		sorted[i] = merged[idx]
+	}
+
+	if limit > 0 && len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+	return sorted
 }
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"avigilon-cli/pkg/models"
+)
+
+// pagingServer serves total events and, like the Web Endpoint, returns a
+// continuation token with every non-empty page, even the last one.
+func pagingServer(t *testing.T, total int) *httptest.Server {
+	t.Helper()
+	next := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		var resp models.EventListResponse
+		for ; next < total && len(resp.Result.Events) < limit; next++ {
+			resp.Result.Events = append(resp.Result.Events, models.Event{ID: fmt.Sprint(next)})
+		}
+		if len(resp.Result.Events) > 0 {
+			resp.Result.Token = "more"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestEventIteratorCapped(t *testing.T) {
+	tests := []struct {
+		name   string
+		total  int
+		limit  int
+		want   int
+		capped bool
+	}{
+		{"exactly the limit", 3, 3, 3, false},
+		{"more than the limit", 4, 3, 3, true},
+		{"below the limit", 2, 3, 2, false},
+		{"no limit", 5, 0, 5, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(ClientConfig{BaseURL: pagingServer(t, tt.total).URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			it := c.SearchEvents(EventQuery{ServerID: "srv", PageSize: 2, Limit: tt.limit})
+			events, err := it.All(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != tt.want {
+				t.Errorf("got %d events, want %d", len(events), tt.want)
+			}
+			if it.Capped() != tt.capped {
+				t.Errorf("Capped() = %v, want %v", it.Capped(), tt.capped)
+			}
+		})
+	}
+}
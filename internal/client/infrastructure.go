@@ -1,46 +1,58 @@
 package client
 
 import (
-	"fmt"
+	"context"
+
+	"github.com/go-resty/resty/v2"
 	"avigilon-cli/pkg/models"
 )
 
-// GetSites fetches the list of ACC Sites (Clusters)
-func (c *AvigilonClient) GetSites() ([]models.Site, error) {
+// GetSitesContext fetches the list of ACC Sites (Clusters)
+func (c *AvigilonClient) GetSitesContext(ctx context.Context) ([]models.Site, error) {
 	var respData models.SiteListResponse
 
 	// Page 54: GET /sites
-	resp, err := c.HTTP.R().
-		SetResult(&respData).
-		Get("/sites")
+	resp, err := c.execute(ctx, resty.MethodGet, "/sites", func(req *resty.Request) {
+		req.SetResult(&respData)
+	})
 
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.IsError() {
-		return nil, fmt.Errorf("failed to get sites: %s", resp.String())
+		return nil, newAPIError("failed to get sites", resp)
 	}
 
 	return respData.Result.Sites, nil
 }
 
-// GetServers fetches the list of Servers in the current cluster
-func (c *AvigilonClient) GetServers() ([]models.Server, error) {
+// GetSites is GetSitesContext using context.Background().
+func (c *AvigilonClient) GetSites() ([]models.Site, error) {
+	return c.GetSitesContext(context.Background())
+}
+
+// GetServersContext fetches the list of Servers in the current cluster
+func (c *AvigilonClient) GetServersContext(ctx context.Context) ([]models.Server, error) {
 	var respData models.ServerListResponse
 
 	// Page 53: GET /server/ids
-	resp, err := c.HTTP.R().
-		SetResult(&respData).
-		Get("/server/ids")
+	resp, err := c.execute(ctx, resty.MethodGet, "/server/ids", func(req *resty.Request) {
+		req.SetResult(&respData)
+	})
 
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.IsError() {
-		return nil, fmt.Errorf("failed to get servers: %s", resp.String())
+		return nil, newAPIError("failed to get servers", resp)
 	}
 
 	return respData.Result.Servers, nil
 }
+
+// GetServers is GetServersContext using context.Background().
+func (c *AvigilonClient) GetServers() ([]models.Server, error) {
+	return c.GetServersContext(context.Background())
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Limit caps the request rate and concurrency of one class of endpoints,
+// to spare the Web Endpoint, which usually runs on a recording server.
+type Limit struct {
+	// Rate is the sustained number of requests per second. Zero is
+	// unlimited.
+	Rate float64
+
+	// Burst is how many requests may start at once after a quiet period,
+	// at least 1.
+	Burst int
+
+	// MaxInFlight caps the number of concurrent requests. Zero is
+	// unlimited.
+	MaxInFlight int
+}
+
+// Limits holds the Limit of each endpoint class: Media covers /media
+// (snapshots), Metadata everything else.
+type Limits struct {
+	Metadata Limit
+	Media    Limit
+}
+
+// DefaultLimits keeps parallel commands well below what a loaded server
+// handles; media requests are far more expensive than metadata ones.
+var DefaultLimits = Limits{
+	Metadata: Limit{Rate: 10, Burst: 20, MaxInFlight: 8},
+	Media:    Limit{Rate: 2, Burst: 4, MaxInFlight: 2},
+}
+
+// Endpoint classes, as reported in wait logs.
+const (
+	classMetadata = "metadata"
+	classMedia    = "media"
+)
+
+// limiter enforces a Limit.
+type limiter struct {
+	class  string
+	bucket *rate.Limiter  // nil if the rate is unlimited
+	slots  chan struct{}  // nil if concurrency is unlimited
+}
+
+func newLimiter(class string, l Limit) *limiter {
+	lim := &limiter{class: class}
+	if l.Rate > 0 {
+		lim.bucket = rate.NewLimiter(rate.Limit(l.Rate), max(l.Burst, 1))
+	}
+	if l.MaxInFlight > 0 {
+		lim.slots = make(chan struct{}, l.MaxInFlight)
+	}
+	return lim
+}
+
+// acquire waits for a free slot and a token. The returned release frees
+// the slot once the request is done.
+func (l *limiter) acquire(ctx context.Context) (release func(), err error) {
+	release = func() {}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+			release = func() { <-l.slots }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if l.bucket != nil {
+		if err := l.bucket.Wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// acquire waits until a request to path is allowed under Config.Limits,
+// and logs the wait at debug level.
+func (c *AvigilonClient) acquire(ctx context.Context, path string) (release func(), err error) {
+	lim := c.metadataLimit
+	if strings.HasPrefix(path, "/media") {
+		lim = c.mediaLimit
+	}
+
+	start := time.Now()
+	release, err = lim.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if waited := time.Since(start); waited >= time.Millisecond && c.Config.Logger != nil {
+		c.Config.Logger.LogAttrs(ctx, slog.LevelDebug, "waited for rate limit",
+			slog.String("class", lim.class),
+			slog.String("path", path),
+			slog.Duration("wait", waited),
+		)
+	}
+	return release, nil
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimitClasses(t *testing.T) {
+	c, err := New(ClientConfig{BaseURL: "http://localhost", Limits: DefaultLimits})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]string{
+		"/media":          classMedia,
+		"/media/snapshot": classMedia,
+		"/cameras":        classMetadata,
+		"/events/search":  classMetadata,
+		"/server/media":   classMetadata,
+		"/login":          classMetadata,
+	} {
+		release, err := c.acquire(context.Background(), path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The class's slot is the one taken
+		lim := c.metadataLimit
+		if want == classMedia {
+			lim = c.mediaLimit
+		}
+		if len(lim.slots) != 1 {
+			t.Errorf("%s: no %s slot taken", path, want)
+		}
+		release()
+	}
+}
+
+func TestLimitInFlight(t *testing.T) {
+	var running, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","result":{}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := New(ClientConfig{BaseURL: srv.URL, Limits: Limits{Metadata: Limit{MaxInFlight: 2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetHealthContext(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if peak.Load() != 2 {
+		t.Errorf("%d requests ran at once, want 2", peak.Load())
+	}
+
+	// Media requests have their own, unlimited, class
+	if c.mediaLimit.slots != nil {
+		t.Error("media requests share the metadata slots")
+	}
+}
+
+func TestLimitCanceled(t *testing.T) {
+	t.Run("waiting for a token", func(t *testing.T) {
+		lim := newLimiter(classMedia, Limit{Rate: 0.1, Burst: 1, MaxInFlight: 1})
+		release, err := lim.acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+
+		// The burst is used up and the next token is ten seconds away
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if _, err := lim.acquire(ctx); err == nil {
+			t.Fatal("acquire() succeeded without a token")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("acquire() returned after %s, want at the deadline", elapsed)
+		}
+
+		// The slot taken while waiting is given back
+		if len(lim.slots) != 0 {
+			t.Errorf("%d slots still taken", len(lim.slots))
+		}
+	})
+
+	t.Run("waiting for a slot", func(t *testing.T) {
+		lim := newLimiter(classMetadata, Limit{MaxInFlight: 1})
+		release, err := lim.acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer release()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := lim.acquire(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("acquire() error = %v, want context.Canceled", err)
+		}
+	})
+
+	t.Run("request", func(t *testing.T) {
+		var requests atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+		}))
+		t.Cleanup(srv.Close)
+
+		c, err := New(ClientConfig{BaseURL: srv.URL, Limits: Limits{Media: Limit{Rate: 0.1, Burst: 1}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if release, err := c.acquire(context.Background(), "/media"); err == nil {
+			release()
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := c.GetSnapshotContext(ctx, "cam-1"); err == nil || requests.Load() != 0 {
+			t.Errorf("GetSnapshotContext() error = %v after %d requests, want none sent", err, requests.Load())
+		}
+	})
+}
@@ -1,36 +1,141 @@
 package client
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-resty/resty/v2"
 )
 
-// GetSnapshot downloads a JPEG snapshot for the given camera ID.
-// Returns the binary byte slice of the image.
+// ErrNotJPEG is returned when /media answers with something other than a
+// JPEG image, such as the HTML error page of a proxy.
+var ErrNotJPEG = errors.New("response is not a JPEG image")
+
+// jpegMagic starts every JPEG file (SOI marker followed by another marker).
+var jpegMagic = []byte{0xFF, 0xD8, 0xFF}
+
+// SnapshotOptions selects the frame and image size of a snapshot. The zero
+// value asks for the live view at the camera's resolution.
+type SnapshotOptions struct {
+	At      time.Time // Frame from recorded video at this time; zero is live
+	Width   int       // Image width in pixels, zero for the camera's own
+	Height  int       // Image height in pixels, zero for the camera's own
+	Quality int       // JPEG quality from 1 to 100, zero for the server default
+}
+
+// Validate checks the options before any request is made. now is the
+// current time, which --at must not be after.
+func (o SnapshotOptions) Validate(now time.Time) error {
+	if o.Width < 0 || o.Height < 0 {
+		return fmt.Errorf("invalid snapshot size %dx%d", o.Width, o.Height)
+	}
+	if o.Quality < 0 || o.Quality > 100 {
+		return fmt.Errorf("invalid snapshot quality %d, expected 1 to 100", o.Quality)
+	}
+	if !o.At.IsZero() && o.At.After(now) {
+		return fmt.Errorf("snapshot time %s is in the future", o.At.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// GetSnapshotContext downloads a JPEG snapshot of the live view of the
+// given camera ID. Returns the binary byte slice of the image.
+func (c *AvigilonClient) GetSnapshotContext(ctx context.Context, cameraID string) ([]byte, error) {
+	return c.GetSnapshotWithOptionsContext(ctx, cameraID, SnapshotOptions{})
+}
+
+// GetSnapshot is GetSnapshotContext using context.Background().
 func (c *AvigilonClient) GetSnapshot(cameraID string) ([]byte, error) {
+	return c.GetSnapshotContext(context.Background(), cameraID)
+}
+
+// GetSnapshotWithOptionsContext downloads a JPEG snapshot of the given
+// camera ID, from recorded video if opts.At is set. The response must be a
+// JPEG; anything else fails with ErrNotJPEG.
+func (c *AvigilonClient) GetSnapshotWithOptionsContext(ctx context.Context, cameraID string, opts SnapshotOptions) ([]byte, error) {
+	if err := opts.Validate(c.Now()); err != nil {
+		return nil, err
+	}
+
 	// Page 44/45 parameters
-	resp, err := c.HTTP.R().
-		SetQueryParam("cameraId", cameraID).
-		SetQueryParam("format", "jpeg"). // Request an image, not video
-		Get("/media")
+	resp, err := c.execute(ctx, resty.MethodGet, "/media", func(req *resty.Request) {
+		req.SetQueryParam("cameraId", cameraID).
+			SetQueryParam("format", "jpeg") // Request an image, not video
+		if !opts.At.IsZero() {
+			req.SetQueryParam("t", opts.At.UTC().Format(AvigilonTimeFormat))
+		}
+		if opts.Width > 0 {
+			req.SetQueryParam("width", strconv.Itoa(opts.Width))
+		}
+		if opts.Height > 0 {
+			req.SetQueryParam("height", strconv.Itoa(opts.Height))
+		}
+		if opts.Quality > 0 {
+			req.SetQueryParam("quality", strconv.Itoa(opts.Quality))
+		}
+	})
 
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.IsError() {
-		return nil, fmt.Errorf("failed to get snapshot: %s", resp.String())
+		return nil, newAPIError("failed to get snapshot", resp)
 	}
 
-	// Basic validation to ensure we actually got an image
-	contentType := resp.Header().Get("Content-Type")
 	if len(resp.Body()) == 0 {
 		return nil, errors.New("response body is empty")
 	}
-	
-	// Avigilon usually returns "image/jpeg", but sometimes generic binary types
-	// We'll trust the successful status code for now.
-	fmt.Printf("Debug: Received Content-Type: %s, Size: %d bytes\n", contentType, len(resp.Body()))
+	if err := checkJPEG(resp.Header().Get("Content-Type"), resp.Body()); err != nil {
+		return nil, err
+	}
 
 	return resp.Body(), nil
 }
+
+// GetSnapshotWithOptions is GetSnapshotWithOptionsContext using
+// context.Background().
+func (c *AvigilonClient) GetSnapshotWithOptions(cameraID string, opts SnapshotOptions) ([]byte, error) {
+	return c.GetSnapshotWithOptionsContext(context.Background(), cameraID, opts)
+}
+
+// checkJPEG makes sure a successful /media response holds a JPEG. Avigilon
+// usually returns "image/jpeg", but sometimes generic binary types, so
+// those are accepted as long as the data starts like a JPEG.
+func checkJPEG(contentType string, body []byte) error {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "", "image/jpeg", "image/jpg", "image/pjpeg", "application/octet-stream", "binary/octet-stream":
+	default:
+		return fmt.Errorf("%w: got %s%s", ErrNotJPEG, mediaType, preview(body))
+	}
+
+	if !bytes.HasPrefix(body, jpegMagic) {
+		return fmt.Errorf("%w: data of %d bytes doesn't start with a JPEG marker%s", ErrNotJPEG, len(body), preview(body))
+	}
+	return nil
+}
+
+// preview quotes the start of a body that looks like text, to show what the
+// server sent instead of an image.
+func preview(body []byte) string {
+	const limit = 80
+	s := string(body[:min(len(body), limit)])
+	for _, r := range s {
+		if r == unicode.ReplacementChar || (!unicode.IsPrint(r) && !unicode.IsSpace(r)) {
+			return ""
+		}
+	}
+	s = strings.Join(strings.Fields(s), " ")
+	if len(body) > limit {
+		s += "..."
+	}
+	return fmt.Sprintf(" (%q)", s)
+}
@@ -1,37 +1,44 @@
 package client
 
 import (
-	"fmt"
+	"context"
+
+	"github.com/go-resty/resty/v2"
 	"avigilon-cli/pkg/models"
 )
 
-// TriggerDigitalOutput activates a digital output.
+// TriggerDigitalOutputContext activates a digital output.
 // targetID: Can be a Camera ID (triggers all outputs) or a specific Digital Output Entity ID.
 // isCamera: Set to true if targetID is a Camera ID.
-func (c *AvigilonClient) TriggerDigitalOutput(sessionID, targetID string, isCamera bool) error {
-	payload := models.TriggerOutputPayload{
-		Session:  sessionID,
-		IsToggle: true, // Default to toggle (momentary trigger)
-	}
+func (c *AvigilonClient) TriggerDigitalOutputContext(ctx context.Context, targetID string, isCamera bool) error {
+	// Page 18: PUT /camera/commands/trigger-digital-output
+	resp, err := c.execute(ctx, resty.MethodPut, "/camera/commands/trigger-digital-output", func(req *resty.Request) {
+		payload := models.TriggerOutputPayload{
+			Session:  c.Session(),
+			IsToggle: true, // Default to toggle (momentary trigger)
+		}
 
-	if isCamera {
-		payload.ID = targetID
-	} else {
-		payload.EntityID = targetID
-	}
+		if isCamera {
+			payload.ID = targetID
+		} else {
+			payload.EntityID = targetID
+		}
 
-	// Page 18: PUT /camera/commands/trigger-digital-output
-	resp, err := c.HTTP.R().
-		SetBody(payload).
-		Put("/camera/commands/trigger-digital-output")
+		req.SetBody(payload)
+	})
 
 	if err != nil {
 		return err
 	}
 
 	if resp.IsError() {
-		return fmt.Errorf("failed to trigger output: %s", resp.String())
+		return newAPIError("failed to trigger output", resp)
 	}
 
 	return nil
 }
+
+// TriggerDigitalOutput is TriggerDigitalOutputContext using context.Background().
+func (c *AvigilonClient) TriggerDigitalOutput(targetID string, isCamera bool) error {
+	return c.TriggerDigitalOutputContext(context.Background(), targetID, isCamera)
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// RetryPolicy controls how requests failing with a transient error are
+// retried: connection resets and refusals, timeouts, 429 and 502/503/504.
+//
+// Only GET requests are retried, since repeating an action such as
+// acknowledging an alarm or triggering an output may perform it twice.
+// Requests made with a context from MarkRetrySafe are retried regardless
+// of their method.
+type RetryPolicy struct {
+	// MaxAttempts is the number of tries including the first one. Zero or
+	// one disables retrying.
+	MaxAttempts int
+
+	// BaseDelay is the backoff before the first retry, doubled for every
+	// further one up to MaxDelay. Each wait is randomized between half and
+	// all of it, so clients don't retry in lockstep. A Retry-After longer
+	// than MaxDelay is not waited for. Zero takes the value of
+	// DefaultRetryPolicy.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultRetryPolicy tries up to three times, waiting about 0.5s and 1s.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+type retrySafeKey struct{}
+
+// MarkRetrySafe returns a context whose requests may be retried even if
+// they are not idempotent. Use it only for actions that are harmless to
+// repeat.
+func MarkRetrySafe(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retrySafeKey{}, true)
+}
+
+// send builds and sends a request within Config.Limits, retrying it
+// according to Config.Retry. build runs again for every attempt.
+func (c *AvigilonClient) send(ctx context.Context, method, path string, build func(req *resty.Request)) (*resty.Response, error) {
+	safe := method == resty.MethodGet || ctx.Value(retrySafeKey{}) != nil
+
+	for attempt := 1; ; attempt++ {
+		release, err := c.acquire(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		req := c.newRequest(ctx)
+		build(req)
+		resp, err := req.Execute(method, path)
+		release()
+
+		if !safe || attempt >= c.Config.Retry.MaxAttempts || ctx.Err() != nil {
+			return resp, err
+		}
+		reason := retryReason(resp, err)
+		if reason == "" {
+			return resp, err
+		}
+		delay, ok := c.Config.Retry.delay(attempt, resp, err)
+		if !ok {
+			return resp, err
+		}
+
+		if c.Config.Logger != nil {
+			c.Config.Logger.LogAttrs(ctx, slog.LevelInfo, "retrying request",
+				slog.String("method", method),
+				slog.String("path", path),
+				slog.Int("attempt", attempt),
+				slog.String("reason", reason),
+				slog.Duration("delay", delay),
+			)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, err
+		case <-timer.C:
+		}
+	}
+}
+
+// retryReason describes why a request failed transiently, or returns ""
+// if it should not be retried.
+func retryReason(resp *resty.Response, err error) string {
+	if err != nil {
+		var netErr net.Error
+		switch {
+		case errors.As(err, &netErr) && netErr.Timeout():
+			return "timeout"
+		case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			return "connection reset"
+		case errors.Is(err, syscall.ECONNREFUSED):
+			return "connection refused"
+		}
+		return ""
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return resp.Status()
+	}
+	return ""
+}
+
+// delay returns the wait before the attempt after attempt: the server's
+// Retry-After if given, else the jittered backoff. It reports false if
+// the server asks to wait longer than MaxDelay.
+func (p RetryPolicy) delay(attempt int, resp *resty.Response, err error) (time.Duration, bool) {
+	base, limit := p.BaseDelay, p.MaxDelay
+	if base <= 0 {
+		base = DefaultRetryPolicy.BaseDelay
+	}
+	if limit <= 0 {
+		limit = DefaultRetryPolicy.MaxDelay
+	}
+
+	if err == nil {
+		if after, ok := retryAfter(resp.Header().Get("Retry-After")); ok {
+			return after, after <= limit
+		}
+	}
+
+	backoff := base << (attempt - 1)
+	if backoff > limit || backoff <= 0 {
+		backoff = limit
+	}
+	return backoff/2 + rand.N(backoff/2+1), true
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,229 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failure makes a test server fail a request in one of the transient ways.
+type failure func(w http.ResponseWriter)
+
+func statusFailure(status int, retryAfter string) failure {
+	return func(w http.ResponseWriter) {
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(status)
+	}
+}
+
+// resetFailure drops the connection with a TCP reset instead of answering.
+func resetFailure(w http.ResponseWriter) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		panic(err)
+	}
+	_ = conn.(*net.TCPConn).SetLinger(0)
+	_ = conn.Close()
+}
+
+// flakyServer fails the first fails requests with fail, then succeeds. It
+// returns the number of requests received.
+func flakyServer(t *testing.T, fails int32, fail failure) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= fails {
+			fail(w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","result":{}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestRetry(t *testing.T) {
+	failures := []struct {
+		name string
+		fail failure
+	}{
+		{"429", statusFailure(http.StatusTooManyRequests, "0")},
+		{"503", statusFailure(http.StatusServiceUnavailable, "")},
+		{"connection reset", resetFailure},
+	}
+	methods := []struct {
+		name    string
+		call    func(ctx context.Context, c *AvigilonClient) error
+		ctx     func(ctx context.Context) context.Context
+		retried bool
+	}{
+		{"GET", func(ctx context.Context, c *AvigilonClient) error {
+			_, err := c.GetHealthContext(ctx)
+			return err
+		}, nil, true},
+		{"POST", func(ctx context.Context, c *AvigilonClient) error {
+			return c.CreateWebhookContext(ctx, "https://hook.example.com", "token", nil, false, 0)
+		}, nil, false},
+		{"POST marked retry-safe", func(ctx context.Context, c *AvigilonClient) error {
+			return c.CreateWebhookContext(ctx, "https://hook.example.com", "token", nil, false, 0)
+		}, MarkRetrySafe, true},
+	}
+
+	for _, f := range failures {
+		for _, m := range methods {
+			t.Run(f.name+"/"+m.name, func(t *testing.T) {
+				srv, requests := flakyServer(t, 1, f.fail)
+				c, err := New(ClientConfig{
+					BaseURL: srv.URL,
+					Retry:   RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond},
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				ctx := context.Background()
+				if m.ctx != nil {
+					ctx = m.ctx(ctx)
+				}
+				err = m.call(ctx, c)
+
+				if m.retried {
+					if err != nil || requests.Load() != 2 {
+						t.Errorf("error %v after %d requests, want success after 2", err, requests.Load())
+					}
+					return
+				}
+				if err == nil || requests.Load() != 1 {
+					t.Errorf("error %v after %d requests, want the failure after 1", err, requests.Load())
+				}
+			})
+		}
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	srv, requests := flakyServer(t, 10, statusFailure(http.StatusServiceUnavailable, ""))
+	c, err := New(ClientConfig{
+		BaseURL: srv.URL,
+		Retry:   RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.GetHealthContext(context.Background()); err == nil {
+		t.Fatal("GetHealthContext() succeeded, want the last 503")
+	}
+	if requests.Load() != 3 {
+		t.Errorf("%d requests, want MaxAttempts", requests.Load())
+	}
+}
+
+func TestRetryAfterBeyondMaxDelay(t *testing.T) {
+	srv, requests := flakyServer(t, 1, statusFailure(http.StatusTooManyRequests, "60"))
+	c, err := New(ClientConfig{
+		BaseURL: srv.URL,
+		Retry:   RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err = c.GetHealthContext(context.Background())
+	if err == nil || requests.Load() != 1 {
+		t.Errorf("error %v after %d requests, want the 429 after 1", err, requests.Load())
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("gave up after %s, want at once", elapsed)
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	srv, requests := flakyServer(t, 1, statusFailure(http.StatusServiceUnavailable, "1"))
+	c, err := New(ClientConfig{
+		BaseURL: srv.URL,
+		Retry:   RetryPolicy{MaxAttempts: 3, MaxDelay: 10 * time.Second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.GetHealthContext(ctx); err == nil {
+		t.Error("GetHealthContext() succeeded, want the 503")
+	}
+	if elapsed := time.Since(start); requests.Load() != 1 || elapsed > 500*time.Millisecond {
+		t.Errorf("%d requests in %s, want the wait cut short after 1", requests.Load(), elapsed)
+	}
+}
+
+// errTransient stands for a network error, for which no header is read.
+var errTransient = &net.OpError{Op: "read", Err: context.DeadlineExceeded}
+
+func TestRetryDelayJitter(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, backoff := range map[int]time.Duration{
+		1:  100 * time.Millisecond,
+		2:  200 * time.Millisecond,
+		4:  800 * time.Millisecond,
+		5:  time.Second,
+		70: time.Second, // the shift overflows
+	} {
+		lowest, highest := backoff, time.Duration(0)
+		for range 200 {
+			d, ok := p.delay(attempt, nil, errTransient)
+			if !ok || d < backoff/2 || d > backoff {
+				t.Fatalf("attempt %d: delay %s, %t; want between %s and %s", attempt, d, ok, backoff/2, backoff)
+			}
+			lowest, highest = min(lowest, d), max(highest, d)
+		}
+		// The waits are spread, not all the same
+		if highest-lowest < backoff/10 {
+			t.Errorf("attempt %d: delays only between %s and %s", attempt, lowest, highest)
+		}
+	}
+
+	// The defaults apply to a zero policy
+	if d, ok := (RetryPolicy{}).delay(1, nil, errTransient); !ok || d < 250*time.Millisecond || d > 500*time.Millisecond {
+		t.Errorf("zero policy: delay %s, %t; want the default backoff", d, ok)
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"5", 5 * time.Second, true},
+		{"-1", 0, false},
+		{"1.5", 0, false},
+		{"soon", 0, false},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+		{"Sun, 06 Nov 1994 08:49:37 GMT", 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %s, %t; want %s, %t", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	// A date in the future is the time left until then
+	future := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+	if got, ok := retryAfter(future); !ok || got < 28*time.Second || got > 30*time.Second {
+		t.Errorf("retryAfter(%q) = %s, %t; want about 30s", future, got, ok)
+	}
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrCertificateChanged is returned when the server presents a certificate
+// that does not match the pinned fingerprint.
+var ErrCertificateChanged = errors.New("server certificate fingerprint changed")
+
+// TLSConfig controls how the client verifies the Web Endpoint server.
+type TLSConfig struct {
+	CAFile      string // PEM bundle trusted in addition to the system roots
+	CertFile    string // Client certificate for mutual TLS
+	KeyFile     string // Private key matching CertFile
+	Fingerprint string // Pinned SHA-256 fingerprint of the server certificate
+	Insecure    bool   // Skip verification entirely (explicit opt-in)
+
+	// TrustOnFirstUse accepts an unknown server certificate if no fingerprint
+	// is pinned yet and reports it through OnFingerprintLearned.
+	TrustOnFirstUse bool
+}
+
+// Fingerprint returns the SHA-256 fingerprint of a DER encoded certificate
+// as lowercase hex.
+func Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeFingerprint accepts fingerprints in the common
+// "AB:CD:..." notation as well as plain hex.
+func normalizeFingerprint(fp string) string {
+	fp = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(fp)), "sha256:")
+	return strings.ReplaceAll(fp, ":", "")
+}
+
+// buildTLSConfig turns the user facing options into a crypto/tls config.
+// learned is called with the server fingerprint when it is trusted on first use.
+func buildTLSConfig(opts TLSConfig, learned func(fingerprint string)) (*tls.Config, error) {
+	cfg := &tls.Config{}
+
+	// 1. Custom CA bundle on top of the system roots
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", opts.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	// 2. Client certificate for mTLS
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	// 3. Server verification mode
+	pinned := normalizeFingerprint(opts.Fingerprint)
+	switch {
+	case opts.Insecure:
+		cfg.InsecureSkipVerify = true
+
+	case pinned != "":
+		// A pinned certificate replaces chain verification, which is what
+		// makes pinning useful for self-signed VMS appliances.
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errors.New("server presented no certificate")
+			}
+			if got := Fingerprint(rawCerts[0]); got != pinned {
+				return fmt.Errorf("%w: expected %s, got %s", ErrCertificateChanged, pinned, got)
+			}
+			return nil
+		}
+
+	case opts.TrustOnFirstUse:
+		var mu sync.Mutex
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errors.New("server presented no certificate")
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			fp := Fingerprint(rawCerts[0])
+			if pinned == "" {
+				pinned = fp
+				if learned != nil {
+					learned(fp)
+				}
+				return nil
+			}
+			if fp != pinned {
+				return fmt.Errorf("%w: expected %s, got %s", ErrCertificateChanged, pinned, fp)
+			}
+			return nil
+		}
+	}
+
+	return cfg, nil
+}
+
+// ProbeTLS connects to addr (host:port) and returns the certificate chain the
+// server presents. verifyErr reports whether a client using opts would accept
+// that chain; err is set only if no handshake was possible at all.
+func ProbeTLS(ctx context.Context, addr string, opts TLSConfig) (certs []*x509.Certificate, verifyErr error, err error) {
+	cfg, err := buildTLSConfig(opts, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg.ServerName = host
+
+	// 1. Fetch the chain without judging it
+	inspect := cfg.Clone()
+	inspect.InsecureSkipVerify = true
+	inspect.VerifyPeerCertificate = nil
+
+	conn, err := (&tls.Dialer{Config: inspect}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	certs = conn.(*tls.Conn).ConnectionState().PeerCertificates
+	conn.Close()
+
+	// 2. Handshake again the way the client would
+	conn, verifyErr = (&tls.Dialer{Config: cfg}).DialContext(ctx, "tcp", addr)
+	if verifyErr == nil {
+		conn.Close()
+	}
+	return certs, verifyErr, nil
+}
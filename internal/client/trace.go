@@ -0,0 +1,75 @@
+package client
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"avigilon-cli/internal/redact"
+)
+
+// traceTransport logs every request sent through next: method, path,
+// status, latency and sizes at info level, plus the query and headers at
+// debug level. Sessions, passwords and tokens are redacted.
+type traceTransport struct {
+	next   http.RoundTripper
+	logger *slog.Logger
+}
+
+func (t traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("path", req.URL.Path),
+		slog.Duration("latency", time.Since(start)),
+	}
+	if req.ContentLength > 0 {
+		attrs = append(attrs, slog.Int64("request_bytes", req.ContentLength))
+	}
+	if err == nil {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+		if resp.ContentLength >= 0 {
+			attrs = append(attrs, slog.Int64("response_bytes", resp.ContentLength))
+		}
+	}
+
+	if t.logger.Enabled(ctx, slog.LevelDebug) {
+		if req.URL.RawQuery != "" {
+			attrs = append(attrs, slog.String("query", redact.Query(req.URL.Query()).Encode()))
+		}
+		attrs = append(attrs, headerGroup("request_headers", req.Header))
+		if err == nil {
+			attrs = append(attrs, headerGroup("response_headers", resp.Header))
+		}
+	}
+
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		t.logger.LogAttrs(ctx, slog.LevelWarn, "http request failed", attrs...)
+		return resp, err
+	}
+	t.logger.LogAttrs(ctx, slog.LevelInfo, "http request", attrs...)
+	return resp, nil
+}
+
+// headerGroup returns h, redacted, as a group of attributes.
+func headerGroup(name string, h http.Header) slog.Attr {
+	h = redact.Header(h)
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var attrs []any
+	for _, key := range keys {
+		for _, v := range h[key] {
+			attrs = append(attrs, slog.String(key, v))
+		}
+	}
+	return slog.Group(name, attrs...)
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// secretServer logs in with the session "secret-session" and sets a
+// secret cookie on every response. It returns the last authorization token
+// it received.
+func secretServer(t *testing.T) (*httptest.Server, *string) {
+	t.Helper()
+	var token string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "id=secret-cookie")
+		if r.URL.Path == "/login" {
+			var login LoginPayload
+			_ = json.NewDecoder(r.Body).Decode(&login)
+			token = login.AuthorizationToken
+			_, _ = w.Write([]byte(`{"status":"success","result":{"session":"secret-session"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","result":{}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &token
+}
+
+func TestTraceRedacted(t *testing.T) {
+	srv, token := secretServer(t)
+	var log bytes.Buffer
+	c, err := New(ClientConfig{
+		BaseURL:   srv.URL,
+		Username:  "admin",
+		Password:  "secret-password",
+		UserNonce: "nonce",
+		UserKey:   "secret-key",
+		Logger:    slog.New(slog.NewTextHandler(&log, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := c.LoginContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetHealthContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateWebhookContext(ctx, "https://hook.example.com", "secret-hook-token", nil, false, 0); err != nil {
+		t.Fatal(err)
+	}
+	// Some endpoints take the session as a query parameter
+	if _, err := c.HTTP.R().SetContext(ctx).SetQueryParam("session", "secret-session").SetQueryParam("cameraId", "cam-1").Get("/media"); err != nil {
+		t.Fatal(err)
+	}
+
+	trace := log.String()
+	if *token == "" {
+		t.Fatal("no authorization token sent")
+	}
+	if strings.Contains(trace, "secret") || strings.Contains(trace, *token) {
+		t.Errorf("trace leaks a secret:\n%s", trace)
+	}
+	for _, want := range []string{
+		"path=/login", "path=/health", "path=/webhooks", "status=200",
+		"request_headers.X-Avg-Session=REDACTED",
+		"response_headers.Set-Cookie=REDACTED",
+		"query=\"cameraId=cam-1&session=REDACTED\"",
+	} {
+		if !strings.Contains(trace, want) {
+			t.Errorf("trace lacks %s:\n%s", want, trace)
+		}
+	}
+}
+
+func TestTraceLevels(t *testing.T) {
+	srv, _ := secretServer(t)
+	var log bytes.Buffer
+	c, err := New(ClientConfig{
+		BaseURL: srv.URL,
+		Logger:  slog.New(slog.NewTextHandler(&log, nil)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetSession("secret-session")
+
+	if _, err := c.GetHealthContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// Headers and the query are only traced at debug level
+	if trace := log.String(); !strings.Contains(trace, "level=INFO msg=\"http request\"") || strings.Contains(trace, "headers") {
+		t.Errorf("info trace:\n%s", trace)
+	}
+}
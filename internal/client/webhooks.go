@@ -1,77 +1,96 @@
 package client
 
 import (
-	"fmt"
+	"context"
+
+	"github.com/go-resty/resty/v2"
 	"avigilon-cli/pkg/models"
 )
 
-// GetWebhooks lists all registered webhooks
-func (c *AvigilonClient) GetWebhooks() ([]models.Webhook, error) {
+// GetWebhooksContext lists all registered webhooks
+func (c *AvigilonClient) GetWebhooksContext(ctx context.Context) ([]models.Webhook, error) {
 	var respData models.WebhookListResponse
 
-	resp, err := c.HTTP.R().
-		SetResult(&respData).
-		Get("/webhooks")
+	resp, err := c.execute(ctx, resty.MethodGet, "/webhooks", func(req *resty.Request) {
+		req.SetResult(&respData)
+	})
 
 	if err != nil {
 		return nil, err
 	}
+
 	if resp.IsError() {
-		return nil, fmt.Errorf("failed to list webhooks: %s", resp.String())
+		return nil, newAPIError("failed to list webhooks", resp)
 	}
 
 	return respData.Result.Webhooks, nil
 }
 
-// CreateWebhook registers a new webhook with full configuration
-// parameters including session ID (for body), auth token, and heartbeat settings.
-func (c *AvigilonClient) CreateWebhook(sessionID, url, authToken string, topics []string, hbEnable bool, hbFreq int) error {
-	payload := models.WebhookPayload{
-		Session: sessionID, // Required field in the body
-		Webhook: models.Webhook{
-			URL:                 url,
-			AuthenticationToken: authToken, // This must not be empty string
-			Heartbeat: &models.Heartbeat{
-				Enable:      hbEnable,
-				FrequencyMs: hbFreq,
-			},
-			EventTopics: &models.EventTopics{
-				Include: topics,
-			},
-		},
-	}
+// GetWebhooks is GetWebhooksContext using context.Background().
+func (c *AvigilonClient) GetWebhooks() ([]models.Webhook, error) {
+	return c.GetWebhooksContext(context.Background())
+}
 
-	resp, err := c.HTTP.R().
-		SetBody(payload).
-		Post("/webhooks")
+// CreateWebhookContext registers a new webhook with full configuration
+// parameters including auth token and heartbeat settings.
+// The current session ID is embedded in the body as the API requires.
+func (c *AvigilonClient) CreateWebhookContext(ctx context.Context, url, authToken string, topics []string, hbEnable bool, hbFreq int) error {
+	resp, err := c.execute(ctx, resty.MethodPost, "/webhooks", func(req *resty.Request) {
+		req.SetBody(models.WebhookPayload{
+			Session: c.Session(), // Required field in the body
+			Webhook: models.Webhook{
+				URL:                 url,
+				AuthenticationToken: authToken, // This must not be empty string
+				Heartbeat: &models.Heartbeat{
+					Enable:      hbEnable,
+					FrequencyMs: hbFreq,
+				},
+				EventTopics: &models.EventTopics{
+					Include: topics,
+				},
+			},
+		})
+	})
 
 	if err != nil {
 		return err
 	}
+
 	if resp.IsError() {
-		return fmt.Errorf("failed to create webhook: %s", resp.String())
+		return newAPIError("failed to create webhook", resp)
 	}
 
 	return nil
 }
 
-// DeleteWebhook removes a webhook by ID
-func (c *AvigilonClient) DeleteWebhook(id string) error {
+// CreateWebhook is CreateWebhookContext using context.Background().
+func (c *AvigilonClient) CreateWebhook(url, authToken string, topics []string, hbEnable bool, hbFreq int) error {
+	return c.CreateWebhookContext(context.Background(), url, authToken, topics, hbEnable, hbFreq)
+}
+
+// DeleteWebhookContext removes a webhook by ID
+func (c *AvigilonClient) DeleteWebhookContext(ctx context.Context, id string) error {
 	// API requires passing IDs in the body for deletion
 	payload := map[string][]string{
 		"ids": {id},
 	}
 
-	resp, err := c.HTTP.R().
-		SetBody(payload).
-		Delete("/webhooks")
+	resp, err := c.execute(ctx, resty.MethodDelete, "/webhooks", func(req *resty.Request) {
+		req.SetBody(payload)
+	})
 
 	if err != nil {
 		return err
 	}
+
 	if resp.IsError() {
-		return fmt.Errorf("failed to delete webhook: %s", resp.String())
+		return newAPIError("failed to delete webhook", resp)
 	}
 
 	return nil
 }
+
+// DeleteWebhook is DeleteWebhookContext using context.Background().
+func (c *AvigilonClient) DeleteWebhook(id string) error {
+	return c.DeleteWebhookContext(context.Background(), id)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -32,13 +34,53 @@ func InitConfig(cfgFile string) {
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
 		// Config loaded successfully
+		if err := migrateLegacyConfig(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to move settings into the %q profile: %v\n", DefaultProfile, err)
+		}
 	}
 }
 
-// SaveSession updates the config file with the new session ID
+// sessionMu serializes session updates, which may come from concurrent
+// requests of the same client (viper itself is not safe for concurrent use).
+var sessionMu sync.Mutex
+
+// SaveSession updates the active profile with the new session ID
 func SaveSession(sessionID string) error {
-	viper.Set("session_id", sessionID)
-	
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	viper.Set(Key("session_id"), sessionID)
+	viper.Set(Key("session_obtained"), now)
+	viper.Set(Key("session_last_used"), now)
+	return Save()
+}
+
+// TouchSession records that the active profile's session was just used.
+// To avoid rewriting the config file on every command, the time is only
+// saved when the previous record is older than sessionTouchInterval.
+func TouchSession() error {
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+
+	last, err := time.Parse(time.RFC3339, viper.GetString(Key("session_last_used")))
+	if err == nil && time.Since(last) < sessionTouchInterval {
+		return nil
+	}
+	viper.Set(Key("session_last_used"), time.Now().UTC().Format(time.RFC3339))
+	return Save()
+}
+
+// sessionTouchInterval is the resolution of the recorded last use.
+const sessionTouchInterval = time.Minute
+
+// ClearSession removes the active profile's session from the config file.
+func ClearSession() error {
+	return removeSettings(Key("session_id"), Key("session_obtained"), Key("session_last_used"))
+}
+
+// Save writes the current configuration to disk, creating the file if needed.
+func Save() error {
 	// Ensure the file exists before writing
 	if err := viper.WriteConfig(); err != nil {
 		// If file doesn't exist, create it
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Credentials holds the secrets needed to log in again once a session expires.
+type Credentials struct {
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	UserNonce     string `json:"nonce"`
+	UserKey       string `json:"key"`
+	IntegrationID string `json:"integration_id,omitempty"`
+}
+
+// ErrNoCredentials is returned when a profile has no saved credentials.
+var ErrNoCredentials = errors.New("no saved credentials")
+
+// ErrNoKeyring is returned by StoreAuto when no OS keyring is reachable.
+// Plain files are only used when StoreFile is asked for explicitly.
+var ErrNoKeyring = errors.New("no OS keyring available")
+
+// Supported credential stores, selected with --credential-store,
+// AVIGILON_CREDENTIAL_STORE or "credential_store" in the config file.
+const (
+	StoreAuto      = "auto"      // Keyring if available, otherwise ErrNoKeyring
+	StoreKeyring   = "keyring"   // OS keyring (freedesktop Secret Service on Linux)
+	StoreEncrypted = "encrypted" // age file encrypted with a passphrase
+	StoreFile      = "file"      // Plain JSON file with mode 0600
+)
+
+// CredentialStore saves the credentials of each profile in one backend.
+type CredentialStore interface {
+	// Name returns the store's kind, e.g. StoreKeyring.
+	Name() string
+	// Load returns ErrNoCredentials if nothing is saved for the profile.
+	Load(profile string) (Credentials, error)
+	Save(profile string, creds Credentials) error
+	// Delete succeeds if nothing is saved for the profile.
+	Delete(profile string) error
+}
+
+// PassphraseFunc asks for the passphrase of the encrypted credential store
+// when AVIGILON_CREDENTIALS_PASSPHRASE is not set. confirm is true when a
+// new file is about to be written, so the passphrase should be entered twice.
+var PassphraseFunc func(confirm bool) (string, error)
+
+// NewCredentialStore returns the store of the given kind. StoreAuto resolves
+// to the keyring when one is reachable and fails with ErrNoKeyring
+// otherwise, so secrets never land in a plain file by default.
+func NewCredentialStore(kind string) (CredentialStore, error) {
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "", StoreAuto:
+		if store := (keyringStore{}); store.available() {
+			return store, nil
+		}
+		return nil, ErrNoKeyring
+	case StoreKeyring:
+		store := keyringStore{}
+		if !store.available() {
+			return nil, fmt.Errorf("%w (is a Secret Service such as gnome-keyring running?)", ErrNoKeyring)
+		}
+		return store, nil
+	case StoreEncrypted:
+		return encryptedStore{}, nil
+	case StoreFile:
+		return fileStore{}, nil
+	}
+	return nil, fmt.Errorf("unknown credential store %q (use auto, keyring, encrypted or file)", kind)
+}
+
+// savedStore returns the store the profile's credentials were saved in.
+// Profiles from older versions have no record and use the plain file.
+func savedStore(profile string) (CredentialStore, error) {
+	kind := viper.GetString(ProfileKey(profile, "credential_store"))
+	if kind == "" {
+		kind = StoreFile
+	}
+	return NewCredentialStore(kind)
+}
+
+// SaveCredentials stores the credentials of the active profile in the given
+// kind of store and records the store in the profile for the next config
+// write. It returns the store actually used, which differs from kind for
+// StoreAuto.
+func SaveCredentials(kind string, creds Credentials) (string, error) {
+	store, err := NewCredentialStore(kind)
+	if err != nil {
+		return "", err
+	}
+
+	profile := ActiveProfile()
+	if err := store.Save(profile, creds); err != nil {
+		return "", err
+	}
+
+	// Don't leave a copy behind in the store used previously
+	if previous, err := savedStore(profile); err == nil && previous.Name() != store.Name() {
+		_ = previous.Delete(profile)
+	}
+
+	viper.Set(Key("credential_store"), store.Name())
+	return store.Name(), nil
+}
+
+// LoadCredentials reads the credentials of the active profile saved by
+// SaveCredentials.
+func LoadCredentials() (Credentials, error) {
+	profile := ActiveProfile()
+	store, err := savedStore(profile)
+	if err != nil {
+		return Credentials{}, err
+	}
+	return store.Load(profile)
+}
+
+// HasCredentials reports whether credentials were saved for the active
+// profile, without unlocking the store they are in.
+func HasCredentials() bool {
+	profile := ActiveProfile()
+	if viper.GetString(ProfileKey(profile, "credential_store")) != "" {
+		return true
+	}
+	path, err := credentialsPath(profile, ".json")
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// deleteCredentials removes the credentials of a profile from every store.
+func deleteCredentials(profile string) error {
+	var errs []error
+	for _, kind := range []string{StoreFile, StoreEncrypted, StoreKeyring} {
+		store, err := NewCredentialStore(kind)
+		if err != nil {
+			continue // No keyring to clean up
+		}
+		if err := store.Delete(profile); err != nil {
+			errs = append(errs, fmt.Errorf("%s store: %w", kind, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// credentialsPath places a profile's credentials file next to the config
+// file, e.g. ~/.avigilon-cli.credentials.json for the default profile and
+// ~/.avigilon-cli.prod.credentials.json for a profile called "prod".
+func credentialsPath(profile, ext string) (string, error) {
+	suffix := ".credentials" + ext
+	if profile = normalizeProfile(profile); profile != DefaultProfile {
+		suffix = "." + profile + suffix
+	}
+
+	if used := viper.ConfigFileUsed(); used != "" {
+		base := filepath.Ext(used)
+		return used[:len(used)-len(base)] + suffix, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".avigilon-cli"+suffix), nil
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/zalando/go-keyring"
+)
+
+var testCreds = Credentials{Username: "admin", Password: "secret", UserNonce: "nonce", UserKey: "key"}
+
+func TestWritePrivate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no Unix permission bits")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "creds.json")
+
+	// An existing file readable by others is replaced, not reused
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writePrivate(path, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode %04o, want 0600", info.Mode().Perm())
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new" {
+		t.Errorf("content %q, want new", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("%d files in the directory, want no temporary file left", len(entries))
+	}
+}
+
+func TestCheckPrivate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no Unix permission bits")
+	}
+	path := filepath.Join(t.TempDir(), "creds.json")
+
+	if err := checkPrivate(path); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("missing file: %v, want ErrNoCredentials", err)
+	}
+	if err := os.WriteFile(path, []byte("{}"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkPrivate(path); err == nil {
+		t.Error("group readable file accepted")
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkPrivate(path); err != nil {
+		t.Errorf("private file refused: %v", err)
+	}
+}
+
+func TestSavedStoreLegacyProfile(t *testing.T) {
+	home := useTempConfig(t, `profiles:
+  default:
+    base_url: https://acc.example.com
+`)
+
+	// Profiles saved before the stores existed have a plain file
+	data := []byte(`{"username":"admin","password":"secret","nonce":"nonce","key":"key"}`)
+	if err := os.WriteFile(filepath.Join(home, ".avigilon-cli.credentials.json"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := savedStore(DefaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.Name() != StoreFile {
+		t.Errorf("store %q, want %q", store.Name(), StoreFile)
+	}
+	if !HasCredentials() {
+		t.Error("HasCredentials() = false")
+	}
+	if creds, err := LoadCredentials(); err != nil || creds != testCreds {
+		t.Errorf("LoadCredentials() = %+v, %v; want %+v", creds, err, testCreds)
+	}
+}
+
+func TestAutoStore(t *testing.T) {
+	useTempConfig(t, "")
+
+	// Without a keyring, auto never falls back to a plain file
+	keyring.MockInitWithError(errors.New("no dbus"))
+	if _, err := SaveCredentials(StoreAuto, testCreds); !errors.Is(err, ErrNoKeyring) {
+		t.Errorf("SaveCredentials(auto) error = %v, want ErrNoKeyring", err)
+	}
+	if HasCredentials() {
+		t.Error("credentials saved somewhere despite the error")
+	}
+
+	keyring.MockInit()
+	kind, err := SaveCredentials(StoreAuto, testCreds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind != StoreKeyring || viper.GetString(Key("credential_store")) != StoreKeyring {
+		t.Errorf("saved in %q, recorded %q; want the keyring", kind, viper.GetString(Key("credential_store")))
+	}
+	if creds, err := LoadCredentials(); err != nil || creds != testCreds {
+		t.Errorf("LoadCredentials() = %+v, %v; want %+v", creds, err, testCreds)
+	}
+}
+
+func TestSaveCredentialsMovesStore(t *testing.T) {
+	home := useTempConfig(t, "")
+	keyring.MockInit()
+
+	if _, err := SaveCredentials(StoreFile, testCreds); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(home, ".avigilon-cli.credentials.json")
+	if _, err := os.Stat(file); err != nil {
+		t.Fatalf("no credentials file: %v", err)
+	}
+
+	// Moving to the keyring removes the plain copy
+	if _, err := SaveCredentials(StoreKeyring, testCreds); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("plain credentials file left behind: %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// fileStore keeps credentials in plain JSON readable only by the current user.
+type fileStore struct{}
+
+func (fileStore) Name() string { return StoreFile }
+
+func (fileStore) Load(profile string) (Credentials, error) {
+	var creds Credentials
+
+	path, err := credentialsPath(profile, ".json")
+	if err != nil {
+		return creds, err
+	}
+
+	if err := checkPrivate(path); err != nil {
+		return creds, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return creds, err
+	}
+
+	err = json.Unmarshal(data, &creds)
+	return creds, err
+}
+
+func (fileStore) Save(profile string, creds Credentials) error {
+	path, err := credentialsPath(profile, ".json")
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writePrivate(path, data)
+}
+
+func (fileStore) Delete(profile string) error {
+	path, err := credentialsPath(profile, ".json")
+	if err != nil {
+		return err
+	}
+	return removeIfExists(path)
+}
+
+// encryptedStore keeps credentials in an age file encrypted with a
+// passphrase, taken from AVIGILON_CREDENTIALS_PASSPHRASE or PassphraseFunc.
+type encryptedStore struct{}
+
+func (encryptedStore) Name() string { return StoreEncrypted }
+
+func (encryptedStore) Load(profile string) (Credentials, error) {
+	var creds Credentials
+
+	path, err := credentialsPath(profile, ".age")
+	if err != nil {
+		return creds, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return creds, ErrNoCredentials
+	}
+	if err != nil {
+		return creds, err
+	}
+	defer f.Close()
+
+	passphrase, err := credentialsPassphrase(false)
+	if err != nil {
+		return creds, err
+	}
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return creds, err
+	}
+
+	r, err := age.Decrypt(f, identity)
+	if err != nil {
+		return creds, fmt.Errorf("failed to decrypt %s (wrong passphrase?): %w", path, err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return creds, err
+	}
+
+	err = json.Unmarshal(data, &creds)
+	return creds, err
+}
+
+func (encryptedStore) Save(profile string, creds Credentials) error {
+	path, err := credentialsPath(profile, ".age")
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := credentialsPassphrase(true)
+	if err != nil {
+		return err
+	}
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipient)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(w).Encode(creds); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return writePrivate(path, buf.Bytes())
+}
+
+func (encryptedStore) Delete(profile string) error {
+	path, err := credentialsPath(profile, ".age")
+	if err != nil {
+		return err
+	}
+	return removeIfExists(path)
+}
+
+func credentialsPassphrase(confirm bool) (string, error) {
+	if env := os.Getenv("AVIGILON_CREDENTIALS_PASSPHRASE"); env != "" {
+		return env, nil
+	}
+	if PassphraseFunc == nil {
+		return "", errors.New("set AVIGILON_CREDENTIALS_PASSPHRASE to unlock the encrypted credentials")
+	}
+
+	passphrase, err := PassphraseFunc(confirm)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(passphrase) == "" {
+		return "", errors.New("passphrase must not be empty")
+	}
+	return passphrase, nil
+}
+
+// writePrivate writes data to a file readable only by the current user. The
+// data goes to a temporary file first, which is renamed over path, so the
+// secrets are never readable by others and a failed write keeps the old file.
+func writePrivate(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp) // No-op once renamed
+
+	// CreateTemp uses 0600 already, but be explicit about it
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// checkPrivate refuses to use a plain credentials file that other users can
+// read. Windows has no Unix permission bits to check.
+func checkPrivate(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNoCredentials
+	}
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("refusing to use %s: mode %04o is accessible by other users (run: chmod 600 %s)",
+			path, info.Mode().Perm(), path)
+	}
+	return nil
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/zalando/go-keyring"
+)
+
+// keyringService is the service name the credentials are filed under.
+const keyringService = "avigilon-cli"
+
+// keyringStore keeps credentials in the OS keyring: the freedesktop Secret
+// Service (GNOME Keyring, KWallet) on Linux, the Keychain on macOS and the
+// Credential Manager on Windows. Each profile is one secret holding JSON.
+type keyringStore struct{}
+
+func (keyringStore) Name() string { return StoreKeyring }
+
+// available probes the keyring with a lookup that is expected to miss.
+func (keyringStore) available() bool {
+	_, err := keyring.Get(keyringService, "probe")
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}
+
+func (keyringStore) Load(profile string) (Credentials, error) {
+	var creds Credentials
+
+	secret, err := keyring.Get(keyringService, normalizeProfile(profile))
+	if errors.Is(err, keyring.ErrNotFound) {
+		return creds, ErrNoCredentials
+	}
+	if err != nil {
+		return creds, err
+	}
+
+	err = json.Unmarshal([]byte(secret), &creds)
+	return creds, err
+}
+
+func (keyringStore) Save(profile string, creds Credentials) error {
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	return keyring.Set(keyringService, normalizeProfile(profile), string(data))
+}
+
+func (keyringStore) Delete(profile string) error {
+	err := keyring.Delete(keyringService, normalizeProfile(profile))
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+	return err
+}
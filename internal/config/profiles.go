@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultProfile is used when no profile is selected by flag, environment
+// or 'profile use'.
+const DefaultProfile = "default"
+
+// legacyKeys are the per-site settings that older versions stored at the
+// top level of the config file.
+var legacyKeys = []string{"base_url", "username", "session_id", "tls"}
+
+// selectedProfile is set from --profile and wins over everything else.
+var selectedProfile string
+
+// SetProfile selects the profile used by this process (the --profile flag).
+func SetProfile(name string) {
+	selectedProfile = normalizeProfile(name)
+}
+
+// ActiveProfile returns the profile in use: --profile, then AVIGILON_PROFILE,
+// then the current_profile saved by 'profile use', then DefaultProfile.
+func ActiveProfile() string {
+	if selectedProfile != "" {
+		return selectedProfile
+	}
+	if env := normalizeProfile(os.Getenv("AVIGILON_PROFILE")); env != "" {
+		return env
+	}
+	if current := normalizeProfile(viper.GetString("current_profile")); current != "" {
+		return current
+	}
+	return DefaultProfile
+}
+
+// Key returns the config key of a setting in the active profile,
+// e.g. Key("base_url") is "profiles.default.base_url".
+func Key(setting string) string {
+	return ProfileKey(ActiveProfile(), setting)
+}
+
+// ProfileKey returns the config key of a setting in the named profile.
+func ProfileKey(profile, setting string) string {
+	return "profiles." + normalizeProfile(profile) + "." + setting
+}
+
+// ValidateProfileName rejects names that can't be used as a config key.
+func ValidateProfileName(name string) error {
+	name = normalizeProfile(name)
+	if name == "" {
+		return fmt.Errorf("profile name must not be empty")
+	}
+	if strings.ContainsAny(name, ". \t/\\") {
+		return fmt.Errorf("invalid profile name %q: must not contain dots, slashes or spaces", name)
+	}
+	return nil
+}
+
+// Profiles returns the names of all profiles in the config file, sorted.
+func Profiles() []string {
+	var names []string
+	for name := range viper.GetStringMap("profiles") {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProfileExists reports whether the config file has a profile called name.
+func ProfileExists(name string) bool {
+	return viper.IsSet("profiles." + normalizeProfile(name))
+}
+
+// UseProfile makes name the profile used when none is selected explicitly.
+func UseProfile(name string) error {
+	if !ProfileExists(name) {
+		return fmt.Errorf("profile %q does not exist", normalizeProfile(name))
+	}
+	viper.Set("current_profile", normalizeProfile(name))
+	return Save()
+}
+
+// DeleteProfile removes a profile and its saved credentials. Deleting the
+// current profile falls back to DefaultProfile.
+func DeleteProfile(name string) error {
+	name = normalizeProfile(name)
+	if !ProfileExists(name) {
+		return fmt.Errorf("profile %q does not exist", name)
+	}
+
+	keys := []string{"profiles." + name}
+	if normalizeProfile(viper.GetString("current_profile")) == name {
+		keys = append(keys, "current_profile")
+	}
+	if err := removeSettings(keys...); err != nil {
+		return err
+	}
+
+	return deleteCredentials(name)
+}
+
+// migrateLegacyConfig moves the settings of a single-site config written by
+// an older version into the default profile.
+func migrateLegacyConfig() error {
+	if !viper.InConfig("base_url") || viper.IsSet("profiles") {
+		return nil
+	}
+
+	for _, key := range legacyKeys {
+		if viper.InConfig(key) {
+			viper.Set(ProfileKey(DefaultProfile, key), viper.Get(key))
+		}
+	}
+	return removeSettings(legacyKeys...)
+}
+
+// removeSettings rewrites the config file without the given keys. Viper has
+// no way to unset a key, so the remaining settings are written out through a
+// fresh instance and read back in.
+func removeSettings(keys ...string) error {
+	settings := viper.AllSettings()
+	for _, key := range keys {
+		deletePath(settings, strings.Split(key, "."))
+	}
+
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(home, ".avigilon-cli.yaml")
+	}
+
+	fresh := viper.New()
+	if err := fresh.MergeConfigMap(settings); err != nil {
+		return err
+	}
+	if err := fresh.WriteConfigAs(path); err != nil {
+		return err
+	}
+
+	viper.SetConfigFile(path)
+	return viper.ReadInConfig()
+}
+
+func deletePath(m map[string]any, path []string) {
+	if len(path) == 1 {
+		delete(m, path[0])
+		return
+	}
+	if child, ok := m[path[0]].(map[string]any); ok {
+		deletePath(child, path[1:])
+	}
+}
+
+// normalizeProfile lower-cases names because viper keys are case-insensitive.
+func normalizeProfile(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
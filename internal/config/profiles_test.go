@@ -0,0 +1,145 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// useTempConfig points HOME at a new directory holding a config file with
+// the given YAML, if any, and reads it in as the CLI does at startup.
+func useTempConfig(t *testing.T, yaml string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AVIGILON_PROFILE", "")
+	SetProfile("")
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	if yaml != "" {
+		if err := os.WriteFile(filepath.Join(home, ".avigilon-cli.yaml"), []byte(yaml), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	InitConfig("")
+	return home
+}
+
+// reread returns the settings in the config file at path.
+func reread(t *testing.T, path string) *viper.Viper {
+	t.Helper()
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestMigrateLegacyConfig(t *testing.T) {
+	home := useTempConfig(t, `base_url: https://acc.example.com
+username: admin
+session_id: s1
+tls:
+  fingerprint: ab:cd
+timeout: 45s
+`)
+
+	for key, want := range map[string]string{
+		"profiles.default.base_url":        "https://acc.example.com",
+		"profiles.default.username":        "admin",
+		"profiles.default.session_id":      "s1",
+		"profiles.default.tls.fingerprint": "ab:cd",
+		"timeout":                          "45s",
+	} {
+		if got := viper.GetString(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if viper.InConfig("base_url") || viper.InConfig("tls") {
+		t.Error("legacy keys still set at the top level")
+	}
+
+	// The file itself is rewritten
+	saved := reread(t, filepath.Join(home, ".avigilon-cli.yaml"))
+	if saved.IsSet("base_url") || saved.GetString("profiles.default.base_url") != "https://acc.example.com" {
+		t.Errorf("config file not migrated: %v", saved.AllSettings())
+	}
+}
+
+func TestMigrateLegacyConfigKeepsProfiles(t *testing.T) {
+	useTempConfig(t, `base_url: https://old.example.com
+profiles:
+  prod:
+    base_url: https://prod.example.com
+`)
+
+	// A config that already has profiles is left alone
+	if viper.IsSet("profiles.default") {
+		t.Error("legacy settings moved into a config that already has profiles")
+	}
+	if got := viper.GetString("profiles.prod.base_url"); got != "https://prod.example.com" {
+		t.Errorf("profiles.prod.base_url = %q", got)
+	}
+}
+
+func TestRemoveSettings(t *testing.T) {
+	home := useTempConfig(t, `current_profile: lab
+profiles:
+  lab:
+    base_url: https://lab.example.com
+    session_id: s1
+  prod:
+    base_url: https://prod.example.com
+`)
+
+	if err := removeSettings("profiles.lab.session_id", "current_profile", "profiles.missing.key"); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := reread(t, filepath.Join(home, ".avigilon-cli.yaml"))
+	for _, v := range []*viper.Viper{viper.GetViper(), saved} {
+		if v.IsSet("profiles.lab.session_id") || v.IsSet("current_profile") {
+			t.Errorf("removed settings still present: %v", v.AllSettings())
+		}
+		if v.GetString("profiles.lab.base_url") != "https://lab.example.com" || v.GetString("profiles.prod.base_url") != "https://prod.example.com" {
+			t.Errorf("other settings lost: %v", v.AllSettings())
+		}
+	}
+}
+
+func TestActiveProfile(t *testing.T) {
+	useTempConfig(t, `current_profile: Lab
+profiles:
+  lab:
+    base_url: https://lab.example.com
+`)
+
+	if got := ActiveProfile(); got != "lab" {
+		t.Errorf("ActiveProfile() = %q, want the current profile, lower-cased", got)
+	}
+	if got := Key("base_url"); got != "profiles.lab.base_url" {
+		t.Errorf("Key(base_url) = %q", got)
+	}
+
+	t.Setenv("AVIGILON_PROFILE", "Prod")
+	if got := ActiveProfile(); got != "prod" {
+		t.Errorf("ActiveProfile() = %q, want AVIGILON_PROFILE", got)
+	}
+
+	SetProfile(" Staging ")
+	t.Cleanup(func() { SetProfile("") })
+	if got := Key("base_url"); got != "profiles.staging.base_url" {
+		t.Errorf("Key(base_url) = %q, want the --profile one", got)
+	}
+}
+
+func TestActiveProfileDefault(t *testing.T) {
+	useTempConfig(t, "")
+	if got := ActiveProfile(); got != DefaultProfile {
+		t.Errorf("ActiveProfile() = %q, want %q", got, DefaultProfile)
+	}
+}
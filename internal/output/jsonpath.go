@@ -0,0 +1,126 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a parsed JSONPath expression. The supported subset covers
+// field access (.name, ['name']), array indexes ([0], [-1]) and wildcards
+// ([*], .*), optionally wrapped in braces as in kubectl: {.items[*].id}.
+type jsonPath []pathStep
+
+type pathStep struct {
+	field    string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+func parseJSONPath(expr string) (jsonPath, error) {
+	s := strings.TrimSpace(expr)
+	if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	s = strings.TrimPrefix(s, "$")
+
+	var path jsonPath
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			name := s[:end]
+			s = s[end:]
+			switch name {
+			case "":
+				if len(s) > 0 && s[0] == '[' {
+					continue
+				}
+				if len(path) == 0 && len(s) == 0 {
+					return path, nil // "." selects the whole item
+				}
+				return nil, fmt.Errorf("invalid jsonpath %q: empty field name", expr)
+			case "*":
+				path = append(path, pathStep{wildcard: true})
+			default:
+				path = append(path, pathStep{field: name})
+			}
+
+		case '[':
+			end := strings.Index(s, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid jsonpath %q: missing ]", expr)
+			}
+			inner := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+			switch {
+			case inner == "*":
+				path = append(path, pathStep{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				path = append(path, pathStep{field: inner[1 : len(inner)-1]})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid jsonpath %q: bad index %q", expr, inner)
+				}
+				path = append(path, pathStep{index: n, isIndex: true})
+			}
+
+		default:
+			return nil, fmt.Errorf("invalid jsonpath %q: expected . or [ at %q", expr, s)
+		}
+	}
+
+	return path, nil
+}
+
+// eval returns every value matched by the path. Missing fields match nothing.
+func (p jsonPath) eval(root any) []any {
+	current := []any{root}
+
+	for _, step := range p {
+		var next []any
+		for _, v := range current {
+			switch node := v.(type) {
+			case map[string]any:
+				switch {
+				case step.wildcard:
+					keys := make([]string, 0, len(node))
+					for k := range node {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, node[k])
+					}
+				case !step.isIndex:
+					if child, ok := node[step.field]; ok {
+						next = append(next, child)
+					}
+				}
+			case []any:
+				switch {
+				case step.wildcard:
+					next = append(next, node...)
+				case step.isIndex:
+					i := step.index
+					if i < 0 {
+						i += len(node)
+					}
+					if i >= 0 && i < len(node) {
+						next = append(next, node[i])
+					}
+				}
+			}
+		}
+		current = next
+	}
+
+	return current
+}
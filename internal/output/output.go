@@ -0,0 +1,415 @@
+// Package output renders command results as tables, CSV, JSON, YAML,
+// NDJSON, Go templates or JSONPath expressions.
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Supported values for Options.Format.
+const (
+	FormatTable    = "table"
+	FormatWide     = "wide"
+	FormatJSON     = "json"
+	FormatYAML     = "yaml"
+	FormatCSV      = "csv"
+	FormatNDJSON   = "ndjson"
+	FormatTemplate = "template"
+	FormatJSONPath = "jsonpath"
+)
+
+// Column describes one field of a table or CSV row.
+type Column[T any] struct {
+	Header string
+	Value  func(T) string
+	Wide   bool // Only shown with -o wide, or when selected with --columns
+	Width  int  // Fixed width in streamed tables, at least the header's
+}
+
+// Options controls how a list is rendered.
+type Options struct {
+	Format    string
+	Template  string   // Argument of template=... or jsonpath=...
+	NoHeaders bool     // Omit the header row of table, wide and csv output
+	Columns   []string // Headers to show, in order (case-insensitive)
+	SortBy    string   // Header of the column to sort by
+}
+
+// ParseOptions parses an --output value such as "csv", "template={{.name}}"
+// or "jsonpath={.id}".
+func ParseOptions(format string) (Options, error) {
+	name, arg, hasArg := strings.Cut(format, "=")
+	opts := Options{Format: strings.ToLower(strings.TrimSpace(name)), Template: arg}
+
+	switch opts.Format {
+	case "":
+		opts.Format = FormatTable
+	case FormatTable, FormatWide, FormatJSON, FormatYAML, FormatCSV, FormatNDJSON:
+		if hasArg {
+			return opts, fmt.Errorf("output format %q takes no argument", opts.Format)
+		}
+	case FormatTemplate, "go-template":
+		opts.Format = FormatTemplate
+		if arg == "" {
+			return opts, fmt.Errorf("template output requires a template, e.g. -o 'template={{.name}}'")
+		}
+		if _, err := template.New("output").Parse(arg); err != nil {
+			return opts, fmt.Errorf("invalid template: %w", err)
+		}
+	case FormatJSONPath:
+		if arg == "" {
+			return opts, fmt.Errorf("jsonpath output requires an expression, e.g. -o 'jsonpath={.id}'")
+		}
+		if _, err := parseJSONPath(arg); err != nil {
+			return opts, err
+		}
+	default:
+		return opts, fmt.Errorf("unknown output format %q (use table, wide, json, yaml, csv, ndjson, template=... or jsonpath=...)", name)
+	}
+
+	return opts, nil
+}
+
+// IsTable reports whether the output is meant for humans rather than scripts.
+func (o Options) IsTable() bool {
+	return o.Format == FormatTable || o.Format == FormatWide
+}
+
+// IsDocument reports whether the output is a single JSON or YAML document.
+func (o Options) IsDocument() bool {
+	return o.Format == FormatJSON || o.Format == FormatYAML
+}
+
+// Print renders items with the given columns.
+func Print[T any](w io.Writer, opts Options, items []T, cols []Column[T]) error {
+	if opts.SortBy != "" {
+		col, ok := findColumn(cols, opts.SortBy)
+		if !ok {
+			return fmt.Errorf("cannot sort by unknown column %q (available: %s)", opts.SortBy, headers(cols))
+		}
+		items = append([]T(nil), items...)
+		sort.SliceStable(items, func(i, j int) bool {
+			return lessNatural(col.Value(items[i]), col.Value(items[j]))
+		})
+	}
+
+	switch opts.Format {
+	case FormatJSON, FormatYAML:
+		if items == nil {
+			items = []T{}
+		}
+		return Encode(w, opts.Format, items)
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatTemplate:
+		return printTemplate(w, opts.Template, items)
+	case FormatJSONPath:
+		return printJSONPath(w, opts.Template, items)
+	}
+
+	selected, err := selectColumns(cols, opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.Format == FormatCSV {
+		return printCSV(w, opts, items, selected)
+	}
+	return printTable(w, opts, items, selected)
+}
+
+// Stream prints a list that arrives in batches, such as followed events.
+// Tables can't be aligned in advance, so their columns are padded to
+// Column.Width. Sorting needs the whole list and is rejected.
+type Stream[T any] struct {
+	w       io.Writer
+	opts    Options
+	cols    []Column[T]
+	started bool
+}
+
+// NewStream validates opts for streaming items with the given columns.
+// JSON and YAML documents can't be streamed; use NDJSON instead.
+func NewStream[T any](w io.Writer, opts Options, cols []Column[T]) (*Stream[T], error) {
+	if opts.SortBy != "" {
+		return nil, fmt.Errorf("cannot sort a stream, --sort-by is not supported here")
+	}
+	if opts.IsDocument() {
+		return nil, fmt.Errorf("%s output can't be streamed, use ndjson", opts.Format)
+	}
+	selected, err := selectColumns(cols, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Stream[T]{w: w, opts: opts, cols: selected}, nil
+}
+
+// Print writes the next batch of items. The first call also writes the
+// header row, so Print(nil) shows it before any item arrived.
+func (s *Stream[T]) Print(items []T) error {
+	opts := s.opts
+	opts.NoHeaders = opts.NoHeaders || s.started
+	s.started = true
+
+	switch opts.Format {
+	case FormatTable, FormatWide:
+		return printFixed(s.w, opts, items, s.cols)
+	case FormatCSV:
+		return printCSV(s.w, opts, items, s.cols)
+	}
+	return Print(s.w, opts, items, s.cols)
+}
+
+// printFixed prints a table with the fixed column widths of a stream.
+func printFixed[T any](w io.Writer, opts Options, items []T, cols []Column[T]) error {
+	row := func(values []string) error {
+		var b strings.Builder
+		for i, v := range values {
+			if i == len(values)-1 {
+				b.WriteString(v)
+				break
+			}
+			fmt.Fprintf(&b, "%-*s   ", max(cols[i].Width, len(cols[i].Header)), v)
+		}
+		b.WriteByte('\n')
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	if !opts.NoHeaders {
+		names := make([]string, len(cols))
+		for i, c := range cols {
+			names[i] = c.Header
+		}
+		if err := row(names); err != nil {
+			return err
+		}
+	}
+	for _, item := range items {
+		values := make([]string, len(cols))
+		for i, c := range cols {
+			values[i] = c.Value(item)
+		}
+		if err := row(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Encode writes v as an indented JSON or YAML document. YAML keys follow the
+// JSON field names so both formats describe the same structure.
+func Encode(w io.Writer, format string, v any) error {
+	if format == FormatYAML {
+		generic, err := toGeneric(v)
+		if err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(generic); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func printTable[T any](w io.Writer, opts Options, items []T, cols []Column[T]) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+
+	if !opts.NoHeaders {
+		var names, rules []string
+		for _, c := range cols {
+			names = append(names, c.Header)
+			rules = append(rules, strings.Repeat("-", len(c.Header)))
+		}
+		fmt.Fprintln(tw, strings.Join(names, "\t"))
+		fmt.Fprintln(tw, strings.Join(rules, "\t"))
+	}
+
+	for _, item := range items {
+		values := make([]string, len(cols))
+		for i, c := range cols {
+			values[i] = c.Value(item)
+		}
+		fmt.Fprintln(tw, strings.Join(values, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func printCSV[T any](w io.Writer, opts Options, items []T, cols []Column[T]) error {
+	cw := csv.NewWriter(w)
+
+	if !opts.NoHeaders {
+		names := make([]string, len(cols))
+		for i, c := range cols {
+			names[i] = c.Header
+		}
+		if err := cw.Write(names); err != nil {
+			return err
+		}
+	}
+
+	for _, item := range items {
+		values := make([]string, len(cols))
+		for i, c := range cols {
+			values[i] = c.Value(item)
+		}
+		if err := cw.Write(values); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// printTemplate executes the template once per item against the item's JSON
+// form, so fields are addressed by their JSON names (e.g. {{.name}}).
+func printTemplate[T any](w io.Writer, text string, items []T) error {
+	tmpl, err := template.New("output").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+
+	for _, item := range items {
+		generic, err := toGeneric(item)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, generic); err != nil {
+			return err
+		}
+		if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printJSONPath evaluates the expression once per item and prints the
+// matches of each item on one line, separated by spaces.
+func printJSONPath[T any](w io.Writer, expr string, items []T) error {
+	path, err := parseJSONPath(expr)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		generic, err := toGeneric(item)
+		if err != nil {
+			return err
+		}
+		var parts []string
+		for _, v := range path.eval(generic) {
+			parts = append(parts, formatScalar(v))
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(parts, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectColumns applies --columns, or drops wide-only columns for -o table.
+func selectColumns[T any](cols []Column[T], opts Options) ([]Column[T], error) {
+	if len(opts.Columns) > 0 {
+		var selected []Column[T]
+		for _, name := range opts.Columns {
+			col, ok := findColumn(cols, name)
+			if !ok {
+				return nil, fmt.Errorf("unknown column %q (available: %s)", name, headers(cols))
+			}
+			selected = append(selected, col)
+		}
+		return selected, nil
+	}
+
+	if opts.Format == FormatWide || opts.Format == FormatCSV {
+		return cols, nil
+	}
+
+	var selected []Column[T]
+	for _, c := range cols {
+		if !c.Wide {
+			selected = append(selected, c)
+		}
+	}
+	return selected, nil
+}
+
+func findColumn[T any](cols []Column[T], name string) (Column[T], bool) {
+	name = strings.TrimSpace(name)
+	for _, c := range cols {
+		if strings.EqualFold(c.Header, name) || strings.EqualFold(strings.ReplaceAll(c.Header, " ", "_"), name) {
+			return c, true
+		}
+	}
+	return Column[T]{}, false
+}
+
+func headers[T any](cols []Column[T]) string {
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.Header
+	}
+	return strings.Join(names, ", ")
+}
+
+// lessNatural compares numerically when both values are numbers.
+func lessNatural(a, b string) bool {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return fa < fb
+	}
+	return strings.ToLower(a) < strings.ToLower(b)
+}
+
+// toGeneric converts v to maps and slices via its JSON encoding.
+func toGeneric(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic any
+	err = json.Unmarshal(data, &generic)
+	return generic, err
+}
+
+func formatScalar(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case nil:
+		return ""
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
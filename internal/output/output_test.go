@@ -0,0 +1,204 @@
+package output
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type item struct {
+	ID     string            `json:"id"`
+	Name   string            `json:"name"`
+	Count  int               `json:"count"`
+	Tags   []string          `json:"tags,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+var items = []item{
+	{ID: "a", Name: "Lobby", Count: 10, Tags: []string{"x", "y"}, Labels: map[string]string{"floor": "1"}},
+	{ID: "b", Name: "parking", Count: 9},
+	{ID: "c", Name: "", Count: 100, Tags: []string{"z"}},
+}
+
+var columns = []Column[item]{
+	{Header: "ID", Value: func(i item) string { return i.ID }},
+	{Header: "NAME", Value: func(i item) string { return i.Name }},
+	{Header: "COUNT", Value: func(i item) string { return strconv.Itoa(i.Count) }},
+	{Header: "FLOOR LABEL", Value: func(i item) string { return i.Labels["floor"] }, Wide: true},
+}
+
+// render prints items with columns under opts, failing the test on error.
+func render(t *testing.T, opts Options) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := Print(&b, opts, items, columns); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+// fields returns the whitespace-separated fields of each line of out.
+func fields(out string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	return lines
+}
+
+func TestColumns(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"table hides wide columns", Options{Format: FormatTable}, []string{"ID NAME COUNT", "-- ---- -----", "a Lobby 10", "b parking 9", "c 100"}},
+		{"wide shows them", Options{Format: FormatWide, NoHeaders: true}, []string{"a Lobby 10 1", "b parking 9", "c 100"}},
+		{"selected, in order", Options{Format: FormatTable, Columns: []string{"count", "Floor_Label"}, NoHeaders: true}, []string{"10 1", "9", "100"}},
+		{"selected by header with spaces", Options{Format: FormatTable, Columns: []string{" floor label "}, NoHeaders: true}, []string{"1", "", ""}},
+	}
+	for _, tt := range tests {
+		if got := fields(render(t, tt.opts)); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s:\n%s\nwant:\n%s", tt.name, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+
+	err := Print(&bytes.Buffer{}, Options{Format: FormatTable, Columns: []string{"ID", "NOPE"}}, items, columns)
+	if err == nil || !strings.Contains(err.Error(), `unknown column "NOPE" (available: ID, NAME, COUNT, FLOOR LABEL)`) {
+		t.Errorf("unknown column: %v", err)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	want := "ID,NAME,COUNT,FLOOR LABEL\na,Lobby,10,1\nb,parking,9,\nc,,100,\n"
+	if got := render(t, Options{Format: FormatCSV}); got != want {
+		t.Errorf("csv:\n%s\nwant:\n%s", got, want)
+	}
+	if got := render(t, Options{Format: FormatCSV, NoHeaders: true, Columns: []string{"name", "id"}}); got != "Lobby,a\nparking,b\n,c\n" {
+		t.Errorf("csv without headers:\n%s", got)
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	tests := []struct {
+		by   string
+		want string
+	}{
+		{"ID", "a b c"},
+		// Numbers sort numerically, not as text
+		{"count", "b a c"},
+		// Case doesn't matter, and a missing value sorts first
+		{"NAME", "c a b"},
+		// Equal (here all missing) values keep their order
+		{"floor_label", "b c a"},
+	}
+	for _, tt := range tests {
+		out := render(t, Options{Format: FormatTable, NoHeaders: true, Columns: []string{"ID"}, SortBy: tt.by})
+		if got := strings.Join(strings.Fields(out), " "); got != tt.want {
+			t.Errorf("--sort-by %s: %s, want %s", tt.by, got, tt.want)
+		}
+	}
+
+	err := Print(&bytes.Buffer{}, Options{Format: FormatJSON, SortBy: "missing"}, items, columns)
+	if err == nil || !strings.Contains(err.Error(), `cannot sort by unknown column "missing"`) {
+		t.Errorf("sort by unknown column: %v", err)
+	}
+}
+
+func TestJSONPath(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"{.id}", "a\nb\nc\n"},
+		{"$.name", "Lobby\nparking\n\n"},
+		{".tags[*]", "x y\n\nz\n"},
+		{"{.tags[-1]}", "y\n\nz\n"},
+		{".tags[5]", "\n\n\n"},
+		{"{.labels['floor']}", "1\n\n\n"},
+		{".labels.*", "1\n\n\n"},
+		{".missing.deeper", "\n\n\n"},
+		{".count", "10\n9\n100\n"},
+		{".", `{"count":10,"id":"a","labels":{"floor":"1"},"name":"Lobby","tags":["x","y"]}` + "\n" + `{"count":9,"id":"b","name":"parking"}` + "\n" + `{"count":100,"id":"c","name":"","tags":["z"]}` + "\n"},
+	}
+	for _, tt := range tests {
+		opts, err := ParseOptions("jsonpath=" + tt.expr)
+		if err != nil {
+			t.Errorf("ParseOptions(jsonpath=%s): %v", tt.expr, err)
+			continue
+		}
+		if got := render(t, opts); got != tt.want {
+			t.Errorf("jsonpath=%s:\n%q\nwant:\n%q", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"{.tags[}", ".tags[x]", "..id", "id"} {
+		if _, err := ParseOptions("jsonpath=" + expr); err == nil {
+			t.Errorf("ParseOptions(jsonpath=%s) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	for _, format := range []string{"", "table", "WIDE", "json", "yaml", "csv", "ndjson", "template={{.id}}", "go-template={{.id}}", "jsonpath={.id}"} {
+		if _, err := ParseOptions(format); err != nil {
+			t.Errorf("ParseOptions(%q): %v", format, err)
+		}
+	}
+	for _, format := range []string{"xml", "json=x", "template=", "template={{.id", "jsonpath="} {
+		if _, err := ParseOptions(format); err == nil {
+			t.Errorf("ParseOptions(%q) succeeded, want an error", format)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	cols := []Column[item]{
+		{Header: "ID", Value: func(i item) string { return i.ID }, Width: 4},
+		{Header: "NAME", Value: func(i item) string { return i.Name }, Width: 2},
+		{Header: "COUNT", Value: func(i item) string { return strconv.Itoa(i.Count) }},
+	}
+
+	var b bytes.Buffer
+	s, err := NewStream(&b, Options{Format: FormatTable}, cols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, batch := range [][]item{nil, items[:1], nil, items[1:]} {
+		if err := s.Print(batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The header comes once, and columns are as wide as Width or their header
+	want := "ID     NAME   COUNT\n" +
+		"a      Lobby   10\n" +
+		"b      parking   9\n" +
+		"c             100\n"
+	if b.String() != want {
+		t.Errorf("stream:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	s, err = NewStream(&b, Options{Format: FormatCSV, NoHeaders: true, Columns: []string{"count"}}, cols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Print(items[:2])
+	_ = s.Print(items[2:])
+	if b.String() != "10\n9\n100\n" {
+		t.Errorf("csv stream without headers:\n%s", b.String())
+	}
+
+	for _, opts := range []Options{
+		{Format: FormatTable, SortBy: "ID"},
+		{Format: FormatJSON},
+		{Format: FormatYAML},
+		{Format: FormatCSV, Columns: []string{"nope"}},
+	} {
+		if _, err := NewStream(&b, opts, cols); err == nil {
+			t.Errorf("NewStream(%+v) succeeded, want an error", opts)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Package redact removes sessions, passwords and tokens from HTTP traffic
+// before it is logged or written to disk.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Placeholder replaces every redacted value.
+const Placeholder = "REDACTED"
+
+// sensitiveHeaders carry the session or other credentials, compared
+// case-insensitively since a header map may hold non-canonical keys.
+var sensitiveHeaders = map[string]bool{
+	"x-avg-session":       true,
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+}
+
+// sensitiveFields are JSON fields and query parameters holding secrets,
+// compared case-insensitively: the session, the login password and signed
+// authorization token, and webhook authentication tokens.
+var sensitiveFields = map[string]bool{
+	"session":             true,
+	"password":            true,
+	"authorizationtoken":  true,
+	"authenticationtoken": true,
+}
+
+// Header returns a copy of h with sensitive headers replaced.
+func Header(h http.Header) http.Header {
+	out := h.Clone()
+	for name := range out {
+		if sensitiveHeaders[strings.ToLower(name)] {
+			out[name] = []string{Placeholder}
+		}
+	}
+	return out
+}
+
+// URL returns u with sensitive query parameters replaced.
+func URL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+	out := *u
+	out.RawQuery = Query(u.Query()).Encode()
+	return out.String()
+}
+
+// Query returns a copy of query with sensitive parameters replaced.
+func Query(query url.Values) url.Values {
+	out := url.Values{}
+	for key, values := range query {
+		if sensitiveFields[strings.ToLower(key)] {
+			out.Set(key, Placeholder)
+		} else {
+			out[key] = append([]string(nil), values...)
+		}
+	}
+	return out
+}
+
+// JSON returns body with the string values of sensitive fields replaced,
+// at any depth. It reports false if body is not JSON.
+func JSON(body []byte) ([]byte, bool) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var doc any
+	if err := dec.Decode(&doc); err != nil || dec.More() {
+		return nil, false
+	}
+	out, err := json.Marshal(redactValue(doc))
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if _, isString := field.(string); isString && sensitiveFields[strings.ToLower(key)] {
+				v[key] = Placeholder
+			} else {
+				v[key] = redactValue(field)
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	}
+	return v
+}
@@ -0,0 +1,97 @@
+package redact
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHeader(t *testing.T) {
+	h := http.Header{
+		"X-Avg-Session": {"secret-session"},
+		"x-avg-session": {"raw-secret-session"},
+		"Authorization": {"Bearer secret-token"},
+		"Set-Cookie":    {"a=secret-cookie", "b=secret-cookie"},
+		"Content-Type":  {"application/json"},
+	}
+
+	out := Header(h)
+	for name, values := range out {
+		for _, v := range values {
+			if strings.Contains(v, "secret") {
+				t.Errorf("%s: %q not redacted", name, v)
+			}
+		}
+	}
+	if out.Get("Content-Type") != "application/json" || out.Get("X-Avg-Session") != Placeholder {
+		t.Errorf("Header() = %v", out)
+	}
+	if h.Get("X-Avg-Session") != "secret-session" {
+		t.Error("Header() changed its argument")
+	}
+}
+
+func TestURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://acc/mt/api/rest/v1/cameras", "https://acc/mt/api/rest/v1/cameras"},
+		{"https://acc/media?session=s1&cameraId=cam-1", "https://acc/media?cameraId=cam-1&session=REDACTED"},
+		{"https://acc/events?Session=s1&SESSION=s2", "https://acc/events?SESSION=REDACTED&Session=REDACTED"},
+		{"https://acc/login?password=p&authorizationToken=t", "https://acc/login?authorizationToken=REDACTED&password=REDACTED"},
+		{"https://acc/webhooks?authenticationToken=t&sessions=2", "https://acc/webhooks?authenticationToken=REDACTED&sessions=2"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := URL(u); got != tt.want {
+			t.Errorf("URL(%s) = %s, want %s", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			"login",
+			`{"username":"admin","password":"p","clientName":"cli","authorizationToken":"t"}`,
+			`{"authorizationToken":"REDACTED","clientName":"cli","password":"REDACTED","username":"admin"}`,
+		},
+		{
+			"nested session",
+			`{"status":"success","result":{"session":"s1","servers":[{"session":"s2","id":"srv-1"}]}}`,
+			`{"result":{"servers":[{"id":"srv-1","session":"REDACTED"}],"session":"REDACTED"},"status":"success"}`,
+		},
+		{
+			"webhook",
+			`{"session":"s1","webhook":{"url":"https://hook","authenticationToken":"t","heartbeat":{"frequencyMs":30000}}}`,
+			`{"session":"REDACTED","webhook":{"authenticationToken":"REDACTED","heartbeat":{"frequencyMs":30000},"url":"https://hook"}}`,
+		},
+		{
+			// Only strings are secrets; a count or an object is kept
+			"non-string fields",
+			`{"session":{"id":"s1","timeout":60},"password":null}`,
+			`{"password":null,"session":{"id":"s1","timeout":60}}`,
+		},
+	}
+	for _, tt := range tests {
+		got, ok := JSON([]byte(tt.body))
+		if !ok || string(got) != tt.want {
+			t.Errorf("%s: JSON() = %s, %t; want %s", tt.name, got, ok, tt.want)
+		}
+	}
+
+	for _, body := range []string{"", "not json", `{"a":1} {"b":2}`} {
+		if _, ok := JSON([]byte(body)); ok {
+			t.Errorf("JSON(%q) reported JSON", body)
+		}
+	}
+}
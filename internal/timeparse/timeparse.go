@@ -0,0 +1,144 @@
+// Package timeparse parses the absolute and relative time expressions
+// accepted by event searches.
+package timeparse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Local date-time layouts, tried in order, interpreted in the caller's location.
+var localLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// Time-of-day layouts, used alone ("22:00") or after a day keyword ("yesterday 22:00").
+var clockLayouts = []string{
+	"15:04:05",
+	"15:04",
+}
+
+// LoadLocation resolves a --tz value. An empty value or "local" selects the
+// system time zone; anything else is an IANA name such as "UTC" or "America/Vancouver".
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" || strings.EqualFold(name, "local") {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// Parse interprets s relative to now. Expressions without an explicit
+// offset are read in loc. Supported forms:
+//
+//	RFC3339         2024-05-01T22:00:00Z, 2024-05-01T22:00:00+02:00
+//	Local time      2024-05-01 22:00[:05], 2024-05-01T22:00, 2024-05-01
+//	Keywords        now, today, yesterday, optionally followed by HH:MM[:SS]
+//	Time of day     22:00 (today)
+//	Relative        30m, 2h ago, -1d12h (all before now)
+func Parse(s string, now time.Time, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, fmt.Errorf("empty time expression")
+	}
+	now = now.In(loc)
+
+	// 1. Absolute timestamps
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	// 2. Day keywords with an optional time of day
+	lower := strings.ToLower(s)
+	if lower == "now" {
+		return now, nil
+	}
+	fields := strings.Fields(lower)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	var day time.Time
+	switch fields[0] {
+	case "today":
+		day = today
+	case "yesterday":
+		day = today.AddDate(0, 0, -1)
+	}
+	if !day.IsZero() {
+		switch len(fields) {
+		case 1:
+			return day, nil
+		case 2:
+			if clock, ok := parseClock(fields[1]); ok {
+				return atClock(day, clock), nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid time expression %q: expected e.g. %q", s, fields[0]+" 22:00")
+	}
+
+	// 3. Time of day today
+	if clock, ok := parseClock(lower); ok {
+		return atClock(today, clock), nil
+	}
+
+	// 4. Relative to now
+	rel := strings.TrimSpace(strings.TrimSuffix(lower, "ago"))
+	rel = strings.TrimPrefix(rel, "-")
+	if d, err := ParseDuration(rel); err == nil {
+		return now.Add(-d), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time expression %q: use RFC3339, \"2006-01-02 15:04\", \"yesterday 22:00\" or a duration like \"2h ago\"", s)
+}
+
+// ParseDuration extends time.ParseDuration with a "d" (24h) unit,
+// e.g. "1d12h" or "7d".
+func ParseDuration(s string) (time.Duration, error) {
+	var days time.Duration
+	if i := strings.Index(s, "d"); i >= 0 {
+		n, err := strconv.Atoi(s[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		days = time.Duration(n) * 24 * time.Hour
+		s = s[i+1:]
+		if s == "" {
+			return days, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	return days + d, nil
+}
+
+// parseClock parses a time of day in "HH:MM[:SS]" form.
+func parseClock(s string) (time.Time, bool) {
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// atClock sets the time of day on day. Using time.Date rather than adding
+// an offset keeps wall-clock times correct across DST changes.
+func atClock(day, clock time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(),
+		clock.Hour(), clock.Minute(), clock.Second(), 0, day.Location())
+}
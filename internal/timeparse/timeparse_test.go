@@ -0,0 +1,159 @@
+package timeparse
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata" // the DST cases need America/Vancouver everywhere
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestParse(t *testing.T) {
+	vancouver := mustLoad(t, "America/Vancouver")
+	// 12:00 in Vancouver on the first day of daylight saving time, which
+	// started at 02:00 and skipped to 03:00
+	dstDay := time.Date(2024, 3, 10, 19, 0, 0, 0, time.UTC)
+	summer := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		now  time.Time
+		loc  *time.Location
+		want string
+	}{
+		// Absolute times; an explicit offset wins over --tz
+		{"2024-05-01T22:00:00Z", summer, vancouver, "2024-05-01T22:00:00Z"},
+		{"2024-05-01T22:00:00+02:00", summer, vancouver, "2024-05-01T20:00:00Z"},
+		{"2024-05-01 22:00", summer, vancouver, "2024-05-02T05:00:00Z"},
+		{"2024-05-01 22:00:05", summer, time.UTC, "2024-05-01T22:00:05Z"},
+		{"2024-05-01T22:00", summer, time.UTC, "2024-05-01T22:00:00Z"},
+		{"2024-05-01", summer, vancouver, "2024-05-01T07:00:00Z"},
+		{"2024-01-15", summer, vancouver, "2024-01-15T08:00:00Z"},
+
+		// Keywords and times of day, on the day in --tz
+		{"now", summer, vancouver, "2024-05-01T12:00:00Z"},
+		{"today", summer, time.UTC, "2024-05-01T00:00:00Z"},
+		{"today", summer, vancouver, "2024-05-01T07:00:00Z"},
+		{"Yesterday", summer, time.UTC, "2024-04-30T00:00:00Z"},
+		{"yesterday 22:00", summer, time.UTC, "2024-04-30T22:00:00Z"},
+		{"  today 06:30:15 ", summer, time.UTC, "2024-05-01T06:30:15Z"},
+		{"22:00", summer, time.UTC, "2024-05-01T22:00:00Z"},
+		{"22:00", time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC), vancouver, "2024-05-01T05:00:00Z"}, // still April 30 there
+
+		// Relative times are before now
+		{"30m", summer, time.UTC, "2024-05-01T11:30:00Z"},
+		{"2h ago", summer, time.UTC, "2024-05-01T10:00:00Z"},
+		{"2hago", summer, time.UTC, "2024-05-01T10:00:00Z"},
+		{"-1d12h", summer, time.UTC, "2024-04-30T00:00:00Z"},
+		{"7d", summer, vancouver, "2024-04-24T12:00:00Z"},
+
+		// Across the DST change wall-clock times keep their hour, while
+		// relative times are elapsed time
+		{"today", dstDay, vancouver, "2024-03-10T08:00:00Z"},
+		{"today 03:00", dstDay, vancouver, "2024-03-10T10:00:00Z"},
+		{"yesterday 22:00", dstDay, vancouver, "2024-03-10T06:00:00Z"},
+		{"2024-03-09 12:00", dstDay, vancouver, "2024-03-09T20:00:00Z"},
+		{"1d", dstDay, vancouver, "2024-03-09T19:00:00Z"}, // 11:00 on the 9th, not 12:00
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.expr, tt.now, tt.loc)
+		if err != nil {
+			t.Errorf("Parse(%q) in %s: %v", tt.expr, tt.loc, err)
+			continue
+		}
+		if s := got.UTC().Format(time.RFC3339); s != tt.want {
+			t.Errorf("Parse(%q) at %s in %s = %s, want %s", tt.expr, tt.now.Format(time.RFC3339), tt.loc, s, tt.want)
+		}
+	}
+}
+
+func TestParseResultLocation(t *testing.T) {
+	vancouver := mustLoad(t, "America/Vancouver")
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// Times read in --tz print in it, so tables show what the user typed
+	for _, expr := range []string{"now", "today", "22:00", "2024-05-01 22:00", "2h"} {
+		got, err := Parse(expr, now, vancouver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Location() != vancouver {
+			t.Errorf("Parse(%q) in %s, want America/Vancouver", expr, got.Location())
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"", "empty time expression"},
+		{"   ", "empty time expression"},
+		{"tomorrow", "invalid time expression"},
+		{"yesterday at 5", `expected e.g. "yesterday 22:00"`},
+		{"today 25:00", `expected e.g. "today 22:00"`},
+		{"today 22:00 UTC", `expected e.g. "today 22:00"`},
+		{"2024-13-01", "invalid time expression"},
+		{"2024-05-01 22", "invalid time expression"},
+		{"2h later", "invalid time expression"},
+		{"1.5d", "invalid time expression"},
+		{"5", "invalid time expression"},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.expr, now, time.UTC)
+		if err == nil {
+			t.Errorf("Parse(%q) = %s, want an error", tt.expr, got)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.expr, err, tt.want)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Duration
+	}{
+		{"90s", 90 * time.Second},
+		{"1d", 24 * time.Hour},
+		{"1d12h", 36 * time.Hour},
+		{"2d30m", 48*time.Hour + 30*time.Minute},
+	}
+	for _, tt := range tests {
+		if got, err := ParseDuration(tt.s); err != nil || got != tt.want {
+			t.Errorf("ParseDuration(%q) = %s, %v; want %s", tt.s, got, err, tt.want)
+		}
+	}
+
+	for _, s := range []string{"", "d", "xd", "1d12", "1w"} {
+		if got, err := ParseDuration(s); err == nil {
+			t.Errorf("ParseDuration(%q) = %s, want an error", s, got)
+		}
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	for _, name := range []string{"", "local", "Local"} {
+		if loc := mustLoad(t, name); loc != time.Local {
+			t.Errorf("LoadLocation(%q) = %s, want the system zone", name, loc)
+		}
+	}
+	if loc := mustLoad(t, "UTC"); loc != time.UTC {
+		t.Errorf("LoadLocation(UTC) = %s", loc)
+	}
+	if _, err := LoadLocation("Mars/Olympus_Mons"); err == nil || !strings.Contains(err.Error(), "unknown time zone") {
+		t.Errorf("LoadLocation() error = %v, want an unknown time zone", err)
+	}
+}
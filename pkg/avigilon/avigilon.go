@@ -0,0 +1,148 @@
+// Package avigilon describes the Avigilon Web Endpoint API as Go interfaces.
+//
+// The HTTP client built by New implements API. Code that depends on the
+// interfaces instead of the concrete client can be tested offline with the
+// in-memory implementation in package fake, or against the HTTP server in
+// package mockserver.
+package avigilon
+
+import (
+	"context"
+	"time"
+
+	"avigilon-cli/internal/client"
+	"avigilon-cli/pkg/models"
+)
+
+// API is the full set of Web Endpoint operations.
+type API interface {
+	CameraAPI
+	AlarmAPI
+	EventAPI
+	MediaAPI
+	OutputAPI
+	WebhookAPI
+	InfrastructureAPI
+}
+
+// CameraAPI lists cameras and controls manual recording.
+type CameraAPI interface {
+	GetCamerasContext(ctx context.Context) ([]models.Camera, error)
+
+	// TriggerManualRecordingContext starts ("START") or stops ("STOP")
+	// recording; duration is in seconds and ignored for STOP.
+	TriggerManualRecordingContext(ctx context.Context, cameraIDs []string, action string, duration int) error
+}
+
+// AlarmAPI lists alarms and acts on them.
+type AlarmAPI interface {
+	GetAlarmsContext(ctx context.Context) ([]models.Alarm, error)
+
+	// UpdateAlarmContext performs action (ACKNOWLEDGE, PURGE, DISMISS).
+	UpdateAlarmContext(ctx context.Context, alarmID, action, note string) error
+}
+
+// EventAPI searches the event history of a server.
+type EventAPI interface {
+	// GetEventsContext returns every event of serverID between from and to
+	// (zero for "until now") matching one of topics.
+	GetEventsContext(ctx context.Context, serverID string, from time.Time, to time.Time, topics []string) ([]models.Event, error)
+}
+
+// MediaAPI fetches images.
+type MediaAPI interface {
+	// GetSnapshotContext returns a JPEG of the camera's current view.
+	GetSnapshotContext(ctx context.Context, cameraID string) ([]byte, error)
+
+	// GetSnapshotWithOptionsContext returns a JPEG of the camera at
+	// opts.At (zero for live), scaled to opts.Width and opts.Height.
+	GetSnapshotWithOptionsContext(ctx context.Context, cameraID string, opts SnapshotOptions) ([]byte, error)
+}
+
+// OutputAPI drives digital outputs.
+type OutputAPI interface {
+	// TriggerDigitalOutputContext toggles the output entity targetID, or
+	// every output of a camera if isCamera is set.
+	TriggerDigitalOutputContext(ctx context.Context, targetID string, isCamera bool) error
+}
+
+// WebhookAPI manages webhook subscriptions.
+type WebhookAPI interface {
+	GetWebhooksContext(ctx context.Context) ([]models.Webhook, error)
+	CreateWebhookContext(ctx context.Context, url, authToken string, topics []string, hbEnable bool, hbFreq int) error
+	DeleteWebhookContext(ctx context.Context, id string) error
+}
+
+// InfrastructureAPI describes the sites and servers of the deployment.
+type InfrastructureAPI interface {
+	GetSitesContext(ctx context.Context) ([]models.Site, error)
+	GetServersContext(ctx context.Context) ([]models.Server, error)
+	GetHealthContext(ctx context.Context) (string, error)
+}
+
+// Optional capabilities, which callers check for with a type assertion.
+// The HTTP client implements all of them, the fake ClockAPI and SessionAPI.
+
+// ClockAPI is implemented by APIs that know the offset of the server clock,
+// like the HTTP client from the Date headers of its responses.
+type ClockAPI interface {
+	// Now returns the local time, from Config.Clock for the HTTP client.
+	Now() time.Time
+
+	// ClockSkew returns how far the server clock is ahead of the local one
+	// (negative if it is behind); ok is false until it is known.
+	ClockSkew() (skew time.Duration, ok bool)
+
+	// MeasureClockSkewContext asks the server for its time.
+	MeasureClockSkewContext(ctx context.Context) (time.Duration, error)
+}
+
+// SessionAPI is implemented by APIs that log in and hold a session.
+type SessionAPI interface {
+	Session() string
+	SetSession(sessionID string)
+	LoginContext(ctx context.Context) (string, error)
+	LogoutSessionContext(ctx context.Context, sessionID string) error
+
+	// CanRelogin reports whether an expired session is renewed without
+	// the caller's help.
+	CanRelogin() bool
+}
+
+// EventSearchAPI is implemented by APIs that page through event searches
+// themselves and search several servers at once, like the HTTP client.
+type EventSearchAPI interface {
+	SearchEventsOnServers(ctx context.Context, servers []models.Server, q EventQuery, concurrency int) ([]models.Event, []ServerSearchResult)
+}
+
+// The HTTP client and its configuration.
+type (
+	Client    = client.AvigilonClient
+	Config    = client.ClientConfig
+	TLSConfig = client.TLSConfig
+	APIError  = client.APIError
+
+	SnapshotOptions    = client.SnapshotOptions
+	EventQuery         = client.EventQuery
+	ServerSearchResult = client.ServerSearchResult
+)
+
+// Errors matched with errors.Is, by both the HTTP client and the fakes.
+var (
+	ErrUnauthorized      = client.ErrUnauthorized
+	ErrNotFound          = client.ErrNotFound
+	ErrRateLimited       = client.ErrRateLimited
+	ErrServerUnavailable = client.ErrServerUnavailable
+)
+
+// New creates an HTTP client for the Web Endpoint at cfg.BaseURL.
+func New(cfg Config) (*Client, error) {
+	return client.New(cfg)
+}
+
+var (
+	_ API            = (*Client)(nil)
+	_ ClockAPI       = (*Client)(nil)
+	_ SessionAPI     = (*Client)(nil)
+	_ EventSearchAPI = (*Client)(nil)
+)
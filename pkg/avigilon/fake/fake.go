@@ -0,0 +1,465 @@
+// Package fake provides an in-memory avigilon.API for tests.
+//
+// Fill in the exported data before use; the methods then behave like a
+// small Web Endpoint: alarms change state, webhooks are created and deleted,
+// and recordings and output triggers are recorded for inspection. Unknown
+// IDs yield errors matching avigilon.ErrNotFound.
+package fake
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"avigilon-cli/pkg/avigilon"
+	"avigilon-cli/pkg/models"
+)
+
+// Recording is a call to TriggerManualRecordingContext.
+type Recording struct {
+	CameraIDs []string
+	Action    string
+	Duration  int
+}
+
+// OutputTrigger is a call to TriggerDigitalOutputContext.
+type OutputTrigger struct {
+	TargetID string
+	IsCamera bool
+}
+
+// API is an in-memory avigilon.API. It is safe for concurrent use once
+// the exported fields are set.
+type API struct {
+	Cameras  []models.Camera
+	Alarms   []models.Alarm
+	Webhooks []models.Webhook
+	Sites    []models.Site
+	Servers  []models.Server
+	Health   string
+
+	// Events holds the event history of each server, keyed by server ID.
+	Events map[string][]models.Event
+
+	// Snapshots holds the JPEG returned for a camera ID. Cameras without an
+	// entry get a generated placeholder image.
+	Snapshots map[string][]byte
+
+	// Errors makes a method fail: the key is the method name without the
+	// Context suffix, e.g. "GetCameras".
+	Errors map[string]error
+
+	// Clock is the local clock, time.Now if nil.
+	Clock func() time.Time
+
+	// Skew is how far the server clock is ahead of Clock. Snapshot times
+	// are checked against the server time.
+	Skew time.Duration
+
+	mu         sync.Mutex
+	recordings []Recording
+	triggers   []OutputTrigger
+	nextID     int
+	session    string
+	logins     int
+}
+
+var (
+	_ avigilon.API        = (*API)(nil)
+	_ avigilon.ClockAPI   = (*API)(nil)
+	_ avigilon.SessionAPI = (*API)(nil)
+)
+
+// New returns an empty fake reporting GOOD health.
+func New() *API {
+	return &API{
+		Health:    `{"status":"success","result":{"status":"GOOD"}}`,
+		Events:    map[string][]models.Event{},
+		Snapshots: map[string][]byte{},
+		Errors:    map[string]error{},
+	}
+}
+
+// Recordings returns the recording requests received so far.
+func (f *API) Recordings() []Recording {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Recording(nil), f.recordings...)
+}
+
+// OutputTriggers returns the output triggers received so far.
+func (f *API) OutputTriggers() []OutputTrigger {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]OutputTrigger(nil), f.triggers...)
+}
+
+// check returns the context error, or the error configured for method.
+func (f *API) check(ctx context.Context, method string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f.Errors[method]
+}
+
+// Now returns the time from Clock, or time.Now.
+func (f *API) Now() time.Time {
+	if f.Clock != nil {
+		return f.Clock()
+	}
+	return time.Now()
+}
+
+// ClockSkew returns Skew; the fake always knows it.
+func (f *API) ClockSkew() (time.Duration, bool) {
+	return f.Skew, true
+}
+
+func (f *API) MeasureClockSkewContext(ctx context.Context) (time.Duration, error) {
+	if err := f.check(ctx, "MeasureClockSkew"); err != nil {
+		return 0, err
+	}
+	return f.Skew, nil
+}
+
+// Logins returns the number of successful logins so far.
+func (f *API) Logins() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.logins
+}
+
+func (f *API) Session() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.session
+}
+
+func (f *API) SetSession(sessionID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.session = sessionID
+}
+
+// LoginContext starts a new session; the fake accepts any credentials.
+func (f *API) LoginContext(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx, "Login"); err != nil {
+		return "", err
+	}
+	f.logins++
+	f.session = "session-" + strconv.Itoa(f.logins)
+	return f.session, nil
+}
+
+// LogoutSessionContext ends sessionID if it is the current session and
+// fails with a 401 otherwise, like the server.
+func (f *API) LogoutSessionContext(ctx context.Context, sessionID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx, "LogoutSession"); err != nil {
+		return err
+	}
+	if sessionID == "" || sessionID != f.session {
+		return &avigilon.APIError{Op: "logout failed", StatusCode: http.StatusUnauthorized, Code: "SESSION_INVALID"}
+	}
+	f.session = ""
+	return nil
+}
+
+// CanRelogin is false: the fake's sessions never expire, so errors set in
+// Errors are final.
+func (f *API) CanRelogin() bool {
+	return false
+}
+
+// notFound builds the error the HTTP client returns for a 404.
+func notFound(op, format string, args ...any) error {
+	return &avigilon.APIError{Op: op, StatusCode: http.StatusNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+func (f *API) GetCamerasContext(ctx context.Context) ([]models.Camera, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx, "GetCameras"); err != nil {
+		return nil, err
+	}
+	return append([]models.Camera(nil), f.Cameras...), nil
+}
+
+func (f *API) TriggerManualRecordingContext(ctx context.Context, cameraIDs []string, action string, duration int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx, "TriggerManualRecording"); err != nil {
+		return err
+	}
+	for _, id := range cameraIDs {
+		if f.camera(id) < 0 {
+			return notFound("failed to trigger recording", "camera %s not found", id)
+		}
+	}
+	if action != "STOP" {
+		action = "START"
+	} else {
+		duration = 0
+	}
+	f.recordings = append(f.recordings, Recording{CameraIDs: append([]string(nil), cameraIDs...), Action: action, Duration: duration})
+	return nil
+}
+
+// camera returns the index of the camera with id, or -1.
+func (f *API) camera(id string) int {
+	for i, c := range f.Cameras {
+		if c.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *API) GetAlarmsContext(ctx context.Context) ([]models.Alarm, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx, "GetAlarms"); err != nil {
+		return nil, err
+	}
+	return append([]models.Alarm(nil), f.Alarms...), nil
+}
+
+// UpdateAlarmContext moves the alarm to ACKNOWLEDGED or DISMISSED, or
+// removes it on PURGE.
+func (f *API) UpdateAlarmContext(ctx context.Context, alarmID, action, note string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx, "UpdateAlarm"); err != nil {
+		return err
+	}
+
+	for i := range f.Alarms {
+		if f.Alarms[i].ID != alarmID {
+			continue
+		}
+		switch strings.ToUpper(action) {
+		case "ACKNOWLEDGE":
+			f.Alarms[i].State = "ACKNOWLEDGED"
+		case "DISMISS":
+			f.Alarms[i].State = "DISMISSED"
+		case "PURGE":
+			f.Alarms = append(f.Alarms[:i], f.Alarms[i+1:]...)
+		default:
+			return &avigilon.APIError{Op: "failed to update alarm", StatusCode: http.StatusBadRequest, Message: "unknown action " + action}
+		}
+		return nil
+	}
+	return notFound("failed to update alarm", "alarm %s not found", alarmID)
+}
+
+// GetEventsContext returns the events of serverID within [from, to] whose
+// type starts with one of topics ("ALL" or no topics match everything).
+func (f *API) GetEventsContext(ctx context.Context, serverID string, from time.Time, to time.Time, topics []string) ([]models.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx, "GetEvents"); err != nil {
+		return nil, err
+	}
+
+	events, ok := f.Events[serverID]
+	if !ok && f.server(serverID) < 0 {
+		return nil, notFound(fmt.Sprintf("failed to search events on server %s", serverID), "server %s not found", serverID)
+	}
+
+	var matched []models.Event
+	for _, e := range events {
+		if MatchEvent(e, from, to, topics) {
+			matched = append(matched, e)
+		}
+	}
+	return matched, nil
+}
+
+// MatchEvent reports whether e lies within [from, to] (zero to means "until
+// now") and its type starts with one of topics. "ALL" or no topics match
+// every type.
+func MatchEvent(e models.Event, from, to time.Time, topics []string) bool {
+	at, err := time.Parse(time.RFC3339, e.Timestamp)
+	if err != nil || at.Before(from) || !to.IsZero() && at.After(to) {
+		return false
+	}
+	if len(topics) == 0 {
+		return true
+	}
+	for _, t := range topics {
+		if t == "ALL" || strings.HasPrefix(e.Type, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddEvent appends e to the event history of serverID.
+func (f *API) AddEvent(serverID string, e models.Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Events == nil {
+		f.Events = map[string][]models.Event{}
+	}
+	f.Events[serverID] = append(f.Events[serverID], e)
+}
+
+// server returns the index of the server with id, or -1.
+func (f *API) server(id string) int {
+	for i, s := range f.Servers {
+		if s.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *API) GetSnapshotContext(ctx context.Context, cameraID string) ([]byte, error) {
+	return f.GetSnapshotWithOptionsContext(ctx, cameraID, avigilon.SnapshotOptions{})
+}
+
+// GetSnapshotWithOptionsContext returns the stored snapshot of the camera
+// as is, or a placeholder of the requested size and quality.
+func (f *API) GetSnapshotWithOptionsContext(ctx context.Context, cameraID string, opts avigilon.SnapshotOptions) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx, "GetSnapshot"); err != nil {
+		return nil, err
+	}
+	if err := opts.Validate(f.Now().Add(f.Skew)); err != nil {
+		return nil, err
+	}
+	if img, ok := f.Snapshots[cameraID]; ok {
+		return img, nil
+	}
+	if f.camera(cameraID) < 0 {
+		return nil, notFound("failed to get snapshot", "camera %s not found", cameraID)
+	}
+	return placeholderJPEG(opts.Width, opts.Height, opts.Quality), nil
+}
+
+// PlaceholderJPEG returns a small grey JPEG image.
+func PlaceholderJPEG() []byte {
+	return placeholderJPEG(0, 0, 0)
+}
+
+// placeholderJPEG returns a grey 16:9 JPEG image. A missing width or
+// height follows from the other one; both default to 16x9.
+func placeholderJPEG(width, height, quality int) []byte {
+	switch {
+	case width == 0 && height == 0:
+		width, height = 16, 9
+	case width == 0:
+		width = max(height*16/9, 1)
+	case height == 0:
+		height = max(width*9/16, 1)
+	}
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0x80
+	}
+
+	var encOpts *jpeg.Options
+	if quality > 0 {
+		encOpts = &jpeg.Options{Quality: quality}
+	}
+	var buf bytes.Buffer
+	_ = jpeg.Encode(&buf, img, encOpts)
+	return buf.Bytes()
+}
+
+func (f *API) TriggerDigitalOutputContext(ctx context.Context, targetID string, isCamera bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx, "TriggerDigitalOutput"); err != nil {
+		return err
+	}
+	if isCamera && f.camera(targetID) < 0 {
+		return notFound("failed to trigger output", "camera %s not found", targetID)
+	}
+	f.triggers = append(f.triggers, OutputTrigger{TargetID: targetID, IsCamera: isCamera})
+	return nil
+}
+
+func (f *API) GetWebhooksContext(ctx context.Context) ([]models.Webhook, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx, "GetWebhooks"); err != nil {
+		return nil, err
+	}
+	return append([]models.Webhook(nil), f.Webhooks...), nil
+}
+
+// CreateWebhookContext adds a webhook with a generated ID.
+func (f *API) CreateWebhookContext(ctx context.Context, url, authToken string, topics []string, hbEnable bool, hbFreq int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx, "CreateWebhook"); err != nil {
+		return err
+	}
+	if authToken == "" {
+		return &avigilon.APIError{Op: "failed to create webhook", StatusCode: http.StatusBadRequest, Message: "authenticationToken must not be empty"}
+	}
+
+	f.nextID++
+	f.Webhooks = append(f.Webhooks, models.Webhook{
+		ID:                  "webhook-" + strconv.Itoa(f.nextID),
+		URL:                 url,
+		AuthenticationToken: authToken,
+		Heartbeat:           &models.Heartbeat{Enable: hbEnable, FrequencyMs: hbFreq},
+		EventTopics:         &models.EventTopics{Include: append([]string(nil), topics...)},
+	})
+	return nil
+}
+
+func (f *API) DeleteWebhookContext(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx, "DeleteWebhook"); err != nil {
+		return err
+	}
+	for i, h := range f.Webhooks {
+		if h.ID == id {
+			f.Webhooks = append(f.Webhooks[:i], f.Webhooks[i+1:]...)
+			return nil
+		}
+	}
+	return notFound("failed to delete webhook", "webhook %s not found", id)
+}
+
+func (f *API) GetSitesContext(ctx context.Context) ([]models.Site, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx, "GetSites"); err != nil {
+		return nil, err
+	}
+	return append([]models.Site(nil), f.Sites...), nil
+}
+
+func (f *API) GetServersContext(ctx context.Context) ([]models.Server, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx, "GetServers"); err != nil {
+		return nil, err
+	}
+	return append([]models.Server(nil), f.Servers...), nil
+}
+
+func (f *API) GetHealthContext(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.check(ctx, "GetHealth"); err != nil {
+		return "", err
+	}
+	return f.Health, nil
+}
@@ -0,0 +1,61 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"avigilon-cli/pkg/avigilon"
+)
+
+func TestSession(t *testing.T) {
+	ctx := context.Background()
+	f := New()
+
+	session, err := f.LoginContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Session() != session || f.Logins() != 1 {
+		t.Errorf("session %q after login %q, %d logins", f.Session(), session, f.Logins())
+	}
+
+	// Only the current session can be ended, like on the server
+	if err := f.LogoutSessionContext(ctx, "other"); !errors.Is(err, avigilon.ErrUnauthorized) {
+		t.Errorf("logout of an unknown session: %v, want ErrUnauthorized", err)
+	}
+	if err := f.LogoutSessionContext(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	if f.Session() != "" {
+		t.Errorf("session %q kept after logout", f.Session())
+	}
+
+	f.Errors["Login"] = &avigilon.APIError{StatusCode: 401}
+	if _, err := f.LoginContext(ctx); !errors.Is(err, avigilon.ErrUnauthorized) {
+		t.Errorf("LoginContext() error = %v, want the configured error", err)
+	}
+}
+
+func TestClock(t *testing.T) {
+	local := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	f := New()
+	f.Snapshots["cam-1"] = PlaceholderJPEG()
+	f.Clock = func() time.Time { return local }
+	f.Skew = -time.Hour
+
+	var clock avigilon.ClockAPI = f
+	if skew, ok := clock.ClockSkew(); !ok || skew != -time.Hour || !clock.Now().Equal(local) {
+		t.Errorf("Now() = %s, ClockSkew() = %s, %t", clock.Now(), skew, ok)
+	}
+
+	// Snapshot times are checked against the server clock, an hour behind
+	ctx := context.Background()
+	if _, err := f.GetSnapshotWithOptionsContext(ctx, "cam-1", avigilon.SnapshotOptions{At: local.Add(-30 * time.Minute)}); err == nil {
+		t.Error("snapshot from the server's future accepted")
+	}
+	if _, err := f.GetSnapshotWithOptionsContext(ctx, "cam-1", avigilon.SnapshotOptions{At: local.Add(-90 * time.Minute)}); err != nil {
+		t.Errorf("snapshot from the server's past refused: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package mockserver
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Failure slows down or fails the requests it matches, before they reach
+// the API. Every and Rate pick which matching requests fail; with neither
+// set, all of them do.
+type Failure struct {
+	Method string // Any method if empty
+	Path   string // Path without BasePath, e.g. "/cameras"; any path if empty
+
+	Status int     // Status to fail with; zero only applies Delay
+	Every  int     // Fail every n-th matching request
+	Rate   float64 // Fail this fraction of matching requests at random
+
+	Delay time.Duration // Added to every matching request
+}
+
+func (f Failure) matches(r *http.Request) bool {
+	return (f.Method == "" || strings.EqualFold(f.Method, r.Method)) &&
+		(f.Path == "" || f.Path == r.URL.Path)
+}
+
+// picks reports whether the n-th matching request fails.
+func (f Failure) picks(n int) bool {
+	switch {
+	case f.Status == 0:
+		return false
+	case f.Every > 0:
+		return n%f.Every == 0
+	case f.Rate > 0:
+		return rand.Float64() < f.Rate
+	}
+	return true
+}
+
+// fail applies the configured failures to r and reports whether it has
+// been answered.
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request) bool {
+	for i, f := range h.opts.Failures {
+		if !f.matches(r) {
+			continue
+		}
+
+		h.mu.Lock()
+		h.requests[i]++
+		n := h.requests[i]
+		h.mu.Unlock()
+
+		if f.Delay > 0 {
+			select {
+			case <-time.After(f.Delay):
+			case <-r.Context().Done():
+				return true
+			}
+		}
+		if !f.picks(n) {
+			continue
+		}
+
+		if f.Status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "1")
+		}
+		writeError(w, f.Status, statusType(f.Status), "injected failure")
+		return true
+	}
+	return false
+}
+
+// statusType turns an HTTP status into an error type like NOT_FOUND.
+func statusType(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "ERROR"
+	}
+	return strings.ToUpper(strings.ReplaceAll(text, " ", "_"))
+}
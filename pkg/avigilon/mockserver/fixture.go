@@ -0,0 +1,156 @@
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+	"avigilon-cli/pkg/avigilon/fake"
+	"avigilon-cli/pkg/models"
+)
+
+// Fixture is the content of a fixture file, in YAML or JSON. Records use
+// the field names of the API responses (e.g. connectionState, ipAddress).
+type Fixture struct {
+	Credentials struct {
+		Username      string `json:"username"`
+		Password      string `json:"password"`
+		Nonce         string `json:"nonce"`
+		Key           string `json:"key"`
+		IntegrationID string `json:"integrationId"`
+	} `json:"credentials"`
+
+	// Health is the state reported by /health, GOOD if empty.
+	Health string `json:"health"`
+
+	// SessionTTL expires sessions idle for this long, e.g. "10m".
+	SessionTTL string `json:"sessionTTL"`
+
+	Sites    []models.Site    `json:"sites"`
+	Servers  []models.Server  `json:"servers"`
+	Cameras  []models.Camera  `json:"cameras"`
+	Alarms   []models.Alarm   `json:"alarms"`
+	Webhooks []models.Webhook `json:"webhooks"`
+
+	// Events holds the history of each server ID. A timestamp may be given
+	// relative to the time the fixture is loaded, e.g. "-90m".
+	Events map[string][]models.Event `json:"events"`
+
+	// Snapshots maps camera IDs to JPEG files, relative to the fixture.
+	// Other cameras get a placeholder image.
+	Snapshots map[string]string `json:"snapshots"`
+
+	Failures []struct {
+		Method string  `json:"method"`
+		Path   string  `json:"path"`
+		Status int     `json:"status"`
+		Every  int     `json:"every"`
+		Rate   float64 `json:"rate"`
+		Delay  string  `json:"delay"`
+	} `json:"failures"`
+
+	dir string
+}
+
+// LoadFixture reads a YAML or JSON fixture file.
+func LoadFixture(path string) (*Fixture, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ParseFixture(raw, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// ParseFixture decodes a YAML or JSON fixture. Snapshot files are looked up
+// relative to dir.
+func ParseFixture(raw []byte, dir string) (*Fixture, error) {
+	// JSON is valid YAML. Going through JSON applies the json tags of the
+	// models, so fixtures look like API responses.
+	var doc any
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	asJSON, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fixture{dir: dir}
+	if err := json.Unmarshal(asJSON, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Build returns the API data and server options described by the fixture,
+// with relative event timestamps resolved against now.
+func (f *Fixture) Build(now time.Time) (*fake.API, Options, error) {
+	data := fake.New()
+	data.Sites = f.Sites
+	data.Servers = f.Servers
+	data.Cameras = f.Cameras
+	data.Alarms = f.Alarms
+	data.Webhooks = f.Webhooks
+	if f.Health != "" {
+		data.Health = fmt.Sprintf(`{"status":"success","result":{"status":%q}}`, f.Health)
+	}
+
+	for serverID, events := range f.Events {
+		for _, e := range events {
+			if strings.HasPrefix(e.Timestamp, "-") || strings.HasPrefix(e.Timestamp, "+") {
+				ago, err := time.ParseDuration(e.Timestamp)
+				if err != nil {
+					return nil, Options{}, fmt.Errorf("event %s: invalid timestamp %q", e.ID, e.Timestamp)
+				}
+				e.Timestamp = now.Add(ago).UTC().Format(time.RFC3339)
+			}
+			data.AddEvent(serverID, e)
+		}
+	}
+
+	for cameraID, file := range f.Snapshots {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(f.dir, file)
+		}
+		img, err := os.ReadFile(file)
+		if err != nil {
+			return nil, Options{}, fmt.Errorf("snapshot for camera %s: %w", cameraID, err)
+		}
+		data.Snapshots[cameraID] = img
+	}
+
+	opts := Options{Credentials: Credentials{
+		Username:      f.Credentials.Username,
+		Password:      f.Credentials.Password,
+		UserNonce:     f.Credentials.Nonce,
+		UserKey:       f.Credentials.Key,
+		IntegrationID: f.Credentials.IntegrationID,
+	}}
+
+	var err error
+	if f.SessionTTL != "" {
+		if opts.SessionTTL, err = time.ParseDuration(f.SessionTTL); err != nil {
+			return nil, Options{}, fmt.Errorf("invalid sessionTTL %q", f.SessionTTL)
+		}
+	}
+
+	for _, ff := range f.Failures {
+		failure := Failure{Method: ff.Method, Path: ff.Path, Status: ff.Status, Every: ff.Every, Rate: ff.Rate}
+		if ff.Delay != "" {
+			if failure.Delay, err = time.ParseDuration(ff.Delay); err != nil {
+				return nil, Options{}, fmt.Errorf("invalid failure delay %q", ff.Delay)
+			}
+		}
+		opts.Failures = append(opts.Failures, failure)
+	}
+
+	return data, opts, nil
+}
@@ -0,0 +1,606 @@
+// Package mockserver serves the Web Endpoint REST API from a fake.API, so
+// the real HTTP client can be tested without a VMS.
+//
+// Logins are checked like on a real server: username and password must
+// match, and the authorization token must carry the configured user nonce,
+// a timestamp within MaxSkew of the server clock, a valid signature over the
+// user key and the expected integration identifier. Every other endpoint
+// except /health requires a session from /login in the x-avg-session header.
+//
+// Beyond the API, the server can expire idle sessions, inject failures and
+// delays, and deliver events and heartbeats to registered webhooks. A
+// Fixture describes all of it in one YAML or JSON file.
+package mockserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"avigilon-cli/internal/auth"
+	"avigilon-cli/internal/client"
+	"avigilon-cli/pkg/avigilon"
+	"avigilon-cli/pkg/avigilon/fake"
+	"avigilon-cli/pkg/models"
+)
+
+// BasePath is the path prefix of the REST API on a real Web Endpoint. The
+// mock server accepts requests with and without it.
+const BasePath = "/mt/api/rest/v1"
+
+// Credentials are what the server accepts at /login.
+type Credentials struct {
+	Username      string
+	Password      string
+	UserNonce     string
+	UserKey       string
+	IntegrationID string // Empty if tokens must not carry one
+}
+
+// Options configure a Handler.
+type Options struct {
+	Credentials
+
+	// Clock is the server clock, used to check login timestamps and sent in
+	// the Date header. time.Now if nil.
+	Clock func() time.Time
+
+	// MaxSkew is how far a login timestamp may be from the server clock,
+	// the Web Endpoint's 5 minutes if zero.
+	MaxSkew time.Duration
+
+	// SessionTTL expires sessions that were not used for this long. Zero
+	// keeps them until logout or ExpireSessions.
+	SessionTTL time.Duration
+
+	// Failures are injected before requests reach the API, see Failure.
+	Failures []Failure
+
+	// Logf reports webhook deliveries and their errors. Nothing is logged
+	// if nil.
+	Logf func(format string, args ...any)
+}
+
+// Handler implements the REST API on top of Data.
+type Handler struct {
+	Data *fake.API
+
+	opts Options
+	mux  *http.ServeMux
+
+	mu       sync.Mutex
+	sessions map[string]time.Time       // Last use by session ID
+	searches map[string][]models.Event // Remaining events by continuation token
+	logins   int
+	requests []int                     // Matching requests seen by each failure
+}
+
+// NewHandler returns a handler serving data.
+func NewHandler(data *fake.API, opts Options) *Handler {
+	if opts.MaxSkew == 0 {
+		opts.MaxSkew = client.MaxClockSkew
+	}
+	h := &Handler{
+		Data:     data,
+		opts:     opts,
+		mux:      http.NewServeMux(),
+		sessions: map[string]time.Time{},
+		searches: map[string][]models.Event{},
+		requests: make([]int, len(opts.Failures)),
+	}
+
+	h.mux.HandleFunc("POST /login", h.login)
+	h.mux.HandleFunc("POST /logout", h.logout)
+	h.mux.HandleFunc("GET /health", h.health)
+
+	h.mux.HandleFunc("GET /cameras", h.authed(h.cameras))
+	h.mux.HandleFunc("POST /camera/record/manual", h.authed(h.record))
+	h.mux.HandleFunc("PUT /camera/commands/trigger-digital-output", h.authed(h.triggerOutput))
+	h.mux.HandleFunc("GET /media", h.authed(h.media))
+	h.mux.HandleFunc("GET /alarms", h.authed(h.alarms))
+	h.mux.HandleFunc("PUT /alarm", h.authed(h.updateAlarm))
+	h.mux.HandleFunc("GET /events/search", h.authed(h.searchEvents))
+	h.mux.HandleFunc("GET /webhooks", h.authed(h.webhooks))
+	h.mux.HandleFunc("POST /webhooks", h.authed(h.createWebhook))
+	h.mux.HandleFunc("DELETE /webhooks", h.authed(h.deleteWebhooks))
+	h.mux.HandleFunc("GET /sites", h.authed(h.sites))
+	h.mux.HandleFunc("GET /server/ids", h.authed(h.servers))
+
+	h.mux.HandleFunc("POST /mock/events", h.injectEvent)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Date", h.now().UTC().Format(http.TimeFormat))
+
+	if rest, ok := strings.CutPrefix(r.URL.Path, BasePath); ok {
+		r.URL.Path = rest
+	}
+	if h.fail(w, r) {
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) now() time.Time {
+	if h.opts.Clock != nil {
+		return h.opts.Clock()
+	}
+	return time.Now()
+}
+
+// Logins returns the number of successful logins so far.
+func (h *Handler) Logins() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.logins
+}
+
+// ExpireSessions invalidates every session, as a server restart would.
+func (h *Handler) ExpireSessions() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sessions = map[string]time.Time{}
+}
+
+// Server is a Handler running on an httptest.Server.
+type Server struct {
+	*httptest.Server
+	*Handler
+}
+
+// New starts a plain HTTP server. Close it when done.
+func New(data *fake.API, opts Options) *Server {
+	h := NewHandler(data, opts)
+	return &Server{Server: httptest.NewServer(h), Handler: h}
+}
+
+// NewTLS starts an HTTPS server with a self-signed certificate.
+func NewTLS(data *fake.API, opts Options) *Server {
+	h := NewHandler(data, opts)
+	return &Server{Server: httptest.NewTLSServer(h), Handler: h}
+}
+
+// ClientConfig returns a client configuration that logs in to s with the
+// accepted credentials.
+func (s *Server) ClientConfig() avigilon.Config {
+	cfg := avigilon.Config{
+		BaseURL:       s.URL,
+		Username:      s.opts.Username,
+		Password:      s.opts.Password,
+		UserNonce:     s.opts.UserNonce,
+		UserKey:       s.opts.UserKey,
+		IntegrationID: s.opts.IntegrationID,
+	}
+	if s.TLS != nil {
+		cfg.TLS.Insecure = true
+	}
+	return cfg
+}
+
+// --- Responses ---
+
+func writeResult(w http.ResponseWriter, result any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"status": "success", "result": result})
+}
+
+func writeError(w http.ResponseWriter, status int, errorType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"status":    "error",
+		"errorType": errorType,
+		"message":   message,
+	})
+}
+
+// writeAPIError answers with the status of an error from Data.
+func writeAPIError(w http.ResponseWriter, err error) {
+	var apiErr *avigilon.APIError
+	if errors.As(err, &apiErr) {
+		writeError(w, apiErr.StatusCode, statusType(apiErr.StatusCode), apiErr.Message)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+}
+
+// randomID returns n random bytes in hex.
+func randomID(n int) string {
+	buf := make([]byte, n)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// --- Sessions ---
+
+func (h *Handler) login(w http.ResponseWriter, r *http.Request) {
+	var p client.LoginPayload
+	if !decode(w, r, &p) {
+		return
+	}
+	if p.Username != h.opts.Username || p.Password != h.opts.Password {
+		writeError(w, http.StatusUnauthorized, "INVALID_CREDENTIALS", "invalid username or password")
+		return
+	}
+	if err := h.verifyToken(p.AuthorizationToken); err != nil {
+		writeError(w, http.StatusForbidden, "INVALID_AUTHORIZATION_TOKEN", err.Error())
+		return
+	}
+
+	session := randomID(16)
+
+	h.mu.Lock()
+	h.sessions[session] = h.now()
+	h.logins++
+	h.mu.Unlock()
+
+	writeResult(w, map[string]string{"session": session})
+}
+
+// verifyToken checks a nonce:timestamp:sha256(timestamp+key)[:integrationId]
+// token against the configured credentials and the server clock.
+func (h *Handler) verifyToken(token string) error {
+	parts := strings.Split(token, ":")
+	if len(parts) != 3 && len(parts) != 4 {
+		return errors.New("malformed authorization token")
+	}
+	if parts[0] != h.opts.UserNonce {
+		return errors.New("unknown user nonce")
+	}
+
+	ts, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", parts[1])
+	}
+	at := time.Unix(ts, 0)
+	if skew := h.now().Sub(at); skew > h.opts.MaxSkew || skew < -h.opts.MaxSkew {
+		return fmt.Errorf("timestamp is %s away from the server time", skew.Round(time.Second))
+	}
+
+	var integrationID string
+	if len(parts) == 4 {
+		integrationID = parts[3]
+	}
+	if integrationID != h.opts.IntegrationID {
+		return errors.New("unexpected integration identifier")
+	}
+
+	if auth.TokenAt(h.opts.UserNonce, h.opts.UserKey, integrationID, at) != token {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// useSession reports whether session is valid and, if so, records its use.
+func (h *Handler) useSession(session string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	last, ok := h.sessions[session]
+	if !ok {
+		return false
+	}
+	if h.opts.SessionTTL > 0 && h.now().Sub(last) > h.opts.SessionTTL {
+		delete(h.sessions, session)
+		return false
+	}
+	h.sessions[session] = h.now()
+	return true
+}
+
+// authed rejects requests without a valid session.
+func (h *Handler) authed(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !h.useSession(r.Header.Get("x-avg-session")) {
+			writeError(w, http.StatusUnauthorized, "SESSION_INVALID", "missing or expired session")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (h *Handler) logout(w http.ResponseWriter, r *http.Request) {
+	session := r.Header.Get("x-avg-session")
+	ok := h.useSession(session)
+
+	h.mu.Lock()
+	delete(h.sessions, session)
+	h.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "SESSION_INVALID", "missing or expired session")
+		return
+	}
+	writeResult(w, map[string]string{})
+}
+
+func (h *Handler) health(w http.ResponseWriter, r *http.Request) {
+	health, err := h.Data.GetHealthContext(r.Context())
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(health))
+}
+
+// --- Cameras and media ---
+
+func (h *Handler) cameras(w http.ResponseWriter, r *http.Request) {
+	cameras, err := h.Data.GetCamerasContext(r.Context())
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeResult(w, map[string]any{"cameras": nonNil(cameras)})
+}
+
+func (h *Handler) record(w http.ResponseWriter, r *http.Request) {
+	var p models.ManualRecordingPayload
+	if !decode(w, r, &p) {
+		return
+	}
+	var duration int
+	if p.MaxDurationSec != nil {
+		duration = *p.MaxDurationSec
+	}
+	if err := h.Data.TriggerManualRecordingContext(r.Context(), p.CameraIDs, p.Action, duration); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeResult(w, map[string]string{})
+}
+
+func (h *Handler) triggerOutput(w http.ResponseWriter, r *http.Request) {
+	var p models.TriggerOutputPayload
+	if !decode(w, r, &p) {
+		return
+	}
+	target, isCamera := p.EntityID, false
+	if p.ID != "" {
+		target, isCamera = p.ID, true
+	}
+	if err := h.Data.TriggerDigitalOutputContext(r.Context(), target, isCamera); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeResult(w, map[string]string{})
+}
+
+// maxSnapshotSide caps the requested width and height of snapshots (8K).
+const maxSnapshotSide = 7680
+
+func (h *Handler) media(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var opts avigilon.SnapshotOptions
+	if raw := q.Get("t"); raw != "" && raw != "live" {
+		at, err := parseTime(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid t: "+err.Error())
+			return
+		}
+		opts.At = at
+	}
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{{"width", &opts.Width}, {"height", &opts.Height}, {"quality", &opts.Quality}} {
+		raw := q.Get(p.name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxSnapshotSide {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid "+p.name+" "+raw)
+			return
+		}
+		*p.dst = n
+	}
+	if err := opts.Validate(h.now()); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", err.Error())
+		return
+	}
+
+	img, err := h.Data.GetSnapshotWithOptionsContext(r.Context(), q.Get("cameraId"), opts)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	_, _ = w.Write(img)
+}
+
+// --- Alarms ---
+
+func (h *Handler) alarms(w http.ResponseWriter, r *http.Request) {
+	alarms, err := h.Data.GetAlarmsContext(r.Context())
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeResult(w, map[string]any{"alarms": nonNil(alarms)})
+}
+
+func (h *Handler) updateAlarm(w http.ResponseWriter, r *http.Request) {
+	var p models.AlarmUpdatePayload
+	if !decode(w, r, &p) {
+		return
+	}
+	if err := h.Data.UpdateAlarmContext(r.Context(), p.ID, p.Action, p.Note); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeResult(w, map[string]string{})
+}
+
+// --- Events ---
+
+// defaultEventLimit is the page size when the request sets none.
+const defaultEventLimit = 100
+
+func (h *Handler) searchEvents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit := defaultEventLimit
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid limit "+raw)
+			return
+		}
+		limit = n
+	}
+
+	var events []models.Event
+	switch q.Get("queryType") {
+	case "TIME_RANGE":
+		from, err := parseTime(q.Get("from"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid from: "+err.Error())
+			return
+		}
+		var to time.Time
+		if raw := q.Get("to"); raw != "" {
+			if to, err = parseTime(raw); err != nil {
+				writeError(w, http.StatusBadRequest, "BAD_REQUEST", "invalid to: "+err.Error())
+				return
+			}
+		}
+		events, err = h.Data.GetEventsContext(r.Context(), q.Get("serverId"), from, to, q["eventTopics"])
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+
+	case "CONTINUE":
+		h.mu.Lock()
+		remaining, ok := h.searches[q.Get("token")]
+		delete(h.searches, q.Get("token"))
+		h.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "unknown or expired continuation token")
+			return
+		}
+		events = remaining
+
+	default:
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "unsupported queryType "+q.Get("queryType"))
+		return
+	}
+
+	var token string
+	if len(events) > limit {
+		token = randomID(8)
+
+		h.mu.Lock()
+		h.searches[token] = events[limit:]
+		h.mu.Unlock()
+		events = events[:limit]
+	}
+	writeResult(w, map[string]any{"events": nonNil(events), "token": token})
+}
+
+// parseTime parses a from/to parameter. RFC 3339 covers the millisecond
+// format the client sends.
+func parseTime(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, errors.New("missing")
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+// --- Webhooks ---
+
+func (h *Handler) webhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.Data.GetWebhooksContext(r.Context())
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeResult(w, map[string]any{"webhooks": nonNil(hooks)})
+}
+
+func (h *Handler) createWebhook(w http.ResponseWriter, r *http.Request) {
+	var p models.WebhookPayload
+	if !decode(w, r, &p) {
+		return
+	}
+
+	var topics []string
+	if p.Webhook.EventTopics != nil {
+		topics = p.Webhook.EventTopics.Include
+	}
+	var hb models.Heartbeat
+	if p.Webhook.Heartbeat != nil {
+		hb = *p.Webhook.Heartbeat
+	}
+
+	err := h.Data.CreateWebhookContext(r.Context(), p.Webhook.URL, p.Webhook.AuthenticationToken, topics, hb.Enable, hb.FrequencyMs)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeResult(w, map[string]string{})
+}
+
+func (h *Handler) deleteWebhooks(w http.ResponseWriter, r *http.Request) {
+	var p struct {
+		IDs []string `json:"ids"`
+	}
+	if !decode(w, r, &p) {
+		return
+	}
+	for _, id := range p.IDs {
+		if err := h.Data.DeleteWebhookContext(r.Context(), id); err != nil {
+			writeAPIError(w, err)
+			return
+		}
+	}
+	writeResult(w, map[string]string{})
+}
+
+// --- Infrastructure ---
+
+func (h *Handler) sites(w http.ResponseWriter, r *http.Request) {
+	sites, err := h.Data.GetSitesContext(r.Context())
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeResult(w, map[string]any{"sites": nonNil(sites)})
+}
+
+func (h *Handler) servers(w http.ResponseWriter, r *http.Request) {
+	servers, err := h.Data.GetServersContext(r.Context())
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	writeResult(w, map[string]any{"servers": nonNil(servers)})
+}
+
+// nonNil makes empty lists encode as [] rather than null.
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,119 @@
+package mockserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"avigilon-cli/pkg/avigilon"
+	"avigilon-cli/pkg/avigilon/fake"
+	"avigilon-cli/pkg/models"
+)
+
+var testCredentials = Credentials{
+	Username:      "administrator",
+	Password:      "secret",
+	UserNonce:     "nonce",
+	UserKey:       "key",
+	IntegrationID: "integration",
+}
+
+func testData() *fake.API {
+	data := fake.New()
+	data.Cameras = []models.Camera{{ID: "cam-1", Name: "Lobby", ConnectionState: "CONNECTED"}}
+	return data
+}
+
+// TestLoginRoundTrip logs the HTTP client in and checks the server accepts
+// exactly the tokens signed with the right key, integration ID and time.
+func TestLoginRoundTrip(t *testing.T) {
+	ahead := func() time.Time { return time.Now().Add(10 * time.Minute) }
+
+	tests := []struct {
+		name    string
+		clock   func() time.Time
+		modify  func(cfg *avigilon.Config)
+		wantErr error
+	}{
+		{"valid", nil, func(cfg *avigilon.Config) {}, nil},
+		{"wrong key", nil, func(cfg *avigilon.Config) { cfg.UserKey = "other" }, avigilon.ErrUnauthorized},
+		{"wrong password", nil, func(cfg *avigilon.Config) { cfg.Password = "other" }, avigilon.ErrUnauthorized},
+		{"missing integration ID", nil, func(cfg *avigilon.Config) { cfg.IntegrationID = "" }, avigilon.ErrUnauthorized},
+		{"server clock ahead", ahead, func(cfg *avigilon.Config) {}, avigilon.ErrUnauthorized},
+		{"server clock ahead, compensated", ahead, func(cfg *avigilon.Config) { cfg.CompensateSkew = true }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := New(testData(), Options{Credentials: testCredentials, Clock: tt.clock})
+			defer srv.Close()
+
+			cfg := srv.ClientConfig()
+			tt.modify(&cfg)
+			api, err := avigilon.New(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			session, err := api.LoginContext(context.Background())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("LoginContext() error = %v, want %v", err, tt.wantErr)
+				}
+				if srv.Logins() != 0 {
+					t.Errorf("%d logins accepted, want none", srv.Logins())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoginContext() error = %v", err)
+			}
+			if session == "" || api.Session() != session {
+				t.Errorf("session %q not kept by the client (has %q)", session, api.Session())
+			}
+
+			cameras, err := api.GetCamerasContext(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cameras) != 1 || cameras[0].ID != "cam-1" {
+				t.Errorf("GetCamerasContext() = %v, want cam-1", cameras)
+			}
+		})
+	}
+}
+
+func TestExpiredSessionRelogin(t *testing.T) {
+	srv := New(testData(), Options{Credentials: testCredentials})
+	defer srv.Close()
+	api, err := avigilon.New(srv.ClientConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// The first call logs in, the next one after a restart logs in again
+	if _, err := api.GetCamerasContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	srv.ExpireSessions()
+	if _, err := api.GetCamerasContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Logins() != 2 {
+		t.Errorf("%d logins, want 2", srv.Logins())
+	}
+
+	// After logout the session is rejected until the next login
+	old := api.Session()
+	if err := api.LogoutContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	api.SetSession(old)
+	if _, err := api.GetCamerasContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Logins() != 3 {
+		t.Errorf("%d logins after logout, want 3", srv.Logins())
+	}
+}
@@ -0,0 +1,154 @@
+package mockserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"avigilon-cli/pkg/avigilon/fake"
+	"avigilon-cli/pkg/models"
+)
+
+// Notification is the body the mock server POSTs to webhook URLs.
+type Notification struct {
+	Type                string         `json:"type"` // EVENT or HEARTBEAT
+	WebhookID           string         `json:"webhookId"`
+	AuthenticationToken string         `json:"authenticationToken"`
+	Time                string         `json:"time"`
+	Events              []models.Event `json:"events,omitempty"`
+}
+
+// deliveryTimeout bounds each webhook POST.
+const deliveryTimeout = 10 * time.Second
+
+// heartbeatTick is how often RunHeartbeats checks for due heartbeats.
+const heartbeatTick = 100 * time.Millisecond
+
+// Emit adds e to the event history of serverID and delivers it to every
+// webhook subscribed to its type. A missing ID or timestamp is filled in.
+// Deliveries run in the background.
+func (h *Handler) Emit(serverID string, e models.Event) models.Event {
+	if e.ID == "" {
+		e.ID = randomID(8)
+	}
+	if e.Timestamp == "" {
+		e.Timestamp = h.now().UTC().Format(time.RFC3339)
+	}
+	if e.Server == "" {
+		servers, _ := h.Data.GetServersContext(context.Background())
+		for _, srv := range servers {
+			if srv.ID == serverID {
+				e.Server = srv.Name
+			}
+		}
+	}
+	h.Data.AddEvent(serverID, e)
+
+	hooks, _ := h.Data.GetWebhooksContext(context.Background())
+	for _, hook := range hooks {
+		var topics []string
+		if hook.EventTopics != nil {
+			topics = hook.EventTopics.Include
+		}
+		if fake.MatchEvent(e, time.Time{}, time.Time{}, topics) {
+			go h.deliver(hook, "EVENT", []models.Event{e})
+		}
+	}
+	return e
+}
+
+// RunHeartbeats sends heartbeats to the webhooks that enabled them, at
+// their requested frequency, until ctx is done.
+func (h *Handler) RunHeartbeats(ctx context.Context) {
+	ticker := time.NewTicker(heartbeatTick)
+	defer ticker.Stop()
+
+	last := map[string]time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		hooks, _ := h.Data.GetWebhooksContext(ctx)
+		now := time.Now()
+		for _, hook := range hooks {
+			hb := hook.Heartbeat
+			if hb == nil || !hb.Enable || hb.FrequencyMs <= 0 {
+				continue
+			}
+			if now.Sub(last[hook.ID]) >= time.Duration(hb.FrequencyMs)*time.Millisecond {
+				last[hook.ID] = now
+				go h.deliver(hook, "HEARTBEAT", nil)
+			}
+		}
+	}
+}
+
+// deliver POSTs one notification to hook.
+func (h *Handler) deliver(hook models.Webhook, kind string, events []models.Event) {
+	body, err := json.Marshal(Notification{
+		Type:                kind,
+		WebhookID:           hook.ID,
+		AuthenticationToken: hook.AuthenticationToken,
+		Time:                h.now().UTC().Format(time.RFC3339),
+		Events:              events,
+	})
+	if err != nil {
+		h.logf("Webhook %s: %v", hook.ID, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		h.logf("Webhook %s: %v", hook.ID, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		h.logf("Webhook %s: %s delivery to %s failed: %v", hook.ID, kind, hook.URL, err)
+		return
+	}
+	resp.Body.Close()
+	h.logf("Webhook %s: %s delivered to %s (%s)", hook.ID, kind, hook.URL, resp.Status)
+}
+
+func (h *Handler) logf(format string, args ...any) {
+	if h.opts.Logf != nil {
+		h.opts.Logf(format, args...)
+	}
+}
+
+// injectEvent serves POST /mock/events, which emits an event as if the
+// VMS had recorded it: {"serverId": "...", "event": {...}}. The server ID
+// defaults to the first server.
+func (h *Handler) injectEvent(w http.ResponseWriter, r *http.Request) {
+	var p struct {
+		ServerID string       `json:"serverId"`
+		Event    models.Event `json:"event"`
+	}
+	if !decode(w, r, &p) {
+		return
+	}
+	if p.ServerID == "" {
+		servers, _ := h.Data.GetServersContext(r.Context())
+		if len(servers) == 0 {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", "serverId is required when there are no servers")
+			return
+		}
+		p.ServerID = servers[0].ID
+	}
+	if p.Event.Type == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", "event.type is required")
+		return
+	}
+	writeResult(w, map[string]any{"event": h.Emit(p.ServerID, p.Event)})
+}
@@ -1,5 +1,7 @@
 package models
 
+import "encoding/json"
+
 // CameraListResponse represents the outer wrapper of the API response
 type CameraListResponse struct {
 	Result struct {
@@ -7,7 +9,8 @@ type CameraListResponse struct {
 	} `json:"result"`
 }
 
-// Camera represents a single Avigilon camera device
+// Camera represents a single Avigilon camera device, as returned by
+// GET /cameras with verbosity=HIGH.
 type Camera struct {
 	ID              string `json:"id"`
 	Name            string `json:"name"`
@@ -16,6 +19,213 @@ type Camera struct {
 	FirmwareVersion string `json:"firmwareVersion"`
 	ConnectionState string `json:"connectionState"`
 	IPAddress       string `json:"ipAddress"` // JSON key is singular string "ipAddress"
+	ServerID        string `json:"serverId"`
 	Connected       bool   `json:"connected"`
 	RecordedData    bool   `json:"recordedData"`
+
+	Location     string `json:"location,omitempty"`
+	LogicalID    int    `json:"logicalId,omitempty"` // Number shown in the ACC Client
+	Manufacturer string `json:"manufacturer,omitempty"`
+	MACAddress   string `json:"macAddress,omitempty"`
+
+	Resolution *Resolution    `json:"resolution,omitempty"`
+	Codec      string         `json:"codec,omitempty"`
+	Streams    []CameraStream `json:"streams,omitempty"`
+
+	PTZ            *PTZCapabilities       `json:"ptz,omitempty"`
+	DigitalInputs  []DigitalIO            `json:"digitalInputs,omitempty"`
+	DigitalOutputs []DigitalIO            `json:"digitalOutputs,omitempty"`
+	Analytics      *AnalyticsCapabilities `json:"analytics,omitempty"`
+	Retention      *Retention             `json:"retention,omitempty"`
+
+	// Extra holds the fields of the API response that Camera doesn't model,
+	// or whose value didn't fit, exactly as received. They are written back
+	// when the camera is encoded, so JSON output never drops data. The
+	// nested types keep their own unknown fields the same way.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Resolution is an image size in pixels.
+type Resolution struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	Extra map[string]json.RawMessage `json:"-"` // Fields not modeled, see Camera.Extra
+}
+
+// CameraStream describes one video stream of a camera.
+type CameraStream struct {
+	ID         string      `json:"id,omitempty"`
+	Name       string      `json:"name,omitempty"` // e.g. "primary", "secondary"
+	Codec      string      `json:"codec,omitempty"`
+	Resolution *Resolution `json:"resolution,omitempty"`
+	FrameRate  float64     `json:"frameRate,omitempty"`
+	BitRate    int         `json:"bitRate,omitempty"` // bits per second
+	Quality    int         `json:"quality,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // Fields not modeled, see Camera.Extra
+}
+
+// PTZCapabilities tells which pan, tilt and zoom controls a camera has.
+type PTZCapabilities struct {
+	Pan     bool `json:"pan"`
+	Tilt    bool `json:"tilt"`
+	Zoom    bool `json:"zoom"`
+	Focus   bool `json:"focus,omitempty"`
+	Presets int  `json:"presets,omitempty"` // Number of preset positions
+
+	Extra map[string]json.RawMessage `json:"-"` // Fields not modeled, see Camera.Extra
+}
+
+// DigitalIO is a digital input or output of a camera. Outputs can be
+// triggered with their ID.
+type DigitalIO struct {
+	ID    string `json:"id"`
+	Name  string `json:"name,omitempty"`
+	State string `json:"state,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // Fields not modeled, see Camera.Extra
+}
+
+// AnalyticsCapabilities lists the video analytics a camera supports.
+type AnalyticsCapabilities struct {
+	ObjectClasses []string `json:"objectClasses,omitempty"` // e.g. "PERSON", "VEHICLE"
+	EventTypes    []string `json:"eventTypes,omitempty"`
+	Appearance    bool     `json:"appearanceSearch,omitempty"`
+	Faces         bool     `json:"faceRecognition,omitempty"`
+	LicensePlates bool     `json:"licensePlateRecognition,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // Fields not modeled, see Camera.Extra
+}
+
+// Retention describes how long recordings of a camera are kept.
+type Retention struct {
+	HighQualityDays int    `json:"highQualityDays,omitempty"`
+	LowQualityDays  int    `json:"lowQualityDays,omitempty"`
+	MaxDays         int    `json:"maxDays,omitempty"`
+	OldestRecording string `json:"oldestRecording,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // Fields not modeled, see Camera.Extra
+}
+
+// camera has the fields of Camera without its JSON methods.
+type camera Camera
+
+// UnmarshalJSON decodes a camera, keeping fields it doesn't model in Extra
+// rather than failing on unexpected values.
+func (c *Camera) UnmarshalJSON(data []byte) error {
+	var decoded camera
+	extra, err := decodeLenient(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*c = Camera(decoded)
+	c.Extra = extra
+	return nil
+}
+
+// MarshalJSON encodes the modeled fields followed by Extra.
+func (c Camera) MarshalJSON() ([]byte, error) {
+	return encodeWithExtra(camera(c), c.Extra)
+}
+
+// The nested types decode leniently too, so unknown fields at any depth
+// survive a round trip.
+type (
+	resolution            Resolution
+	cameraStream          CameraStream
+	ptzCapabilities       PTZCapabilities
+	digitalIO             DigitalIO
+	analyticsCapabilities AnalyticsCapabilities
+	retention             Retention
+)
+
+func (r *Resolution) UnmarshalJSON(data []byte) error {
+	var decoded resolution
+	extra, err := decodeLenient(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*r = Resolution(decoded)
+	r.Extra = extra
+	return nil
+}
+
+func (r Resolution) MarshalJSON() ([]byte, error) {
+	return encodeWithExtra(resolution(r), r.Extra)
+}
+
+func (s *CameraStream) UnmarshalJSON(data []byte) error {
+	var decoded cameraStream
+	extra, err := decodeLenient(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*s = CameraStream(decoded)
+	s.Extra = extra
+	return nil
+}
+
+func (s CameraStream) MarshalJSON() ([]byte, error) {
+	return encodeWithExtra(cameraStream(s), s.Extra)
+}
+
+func (p *PTZCapabilities) UnmarshalJSON(data []byte) error {
+	var decoded ptzCapabilities
+	extra, err := decodeLenient(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*p = PTZCapabilities(decoded)
+	p.Extra = extra
+	return nil
+}
+
+func (p PTZCapabilities) MarshalJSON() ([]byte, error) {
+	return encodeWithExtra(ptzCapabilities(p), p.Extra)
+}
+
+func (d *DigitalIO) UnmarshalJSON(data []byte) error {
+	var decoded digitalIO
+	extra, err := decodeLenient(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*d = DigitalIO(decoded)
+	d.Extra = extra
+	return nil
+}
+
+func (d DigitalIO) MarshalJSON() ([]byte, error) {
+	return encodeWithExtra(digitalIO(d), d.Extra)
+}
+
+func (a *AnalyticsCapabilities) UnmarshalJSON(data []byte) error {
+	var decoded analyticsCapabilities
+	extra, err := decodeLenient(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*a = AnalyticsCapabilities(decoded)
+	a.Extra = extra
+	return nil
+}
+
+func (a AnalyticsCapabilities) MarshalJSON() ([]byte, error) {
+	return encodeWithExtra(analyticsCapabilities(a), a.Extra)
+}
+
+func (r *Retention) UnmarshalJSON(data []byte) error {
+	var decoded retention
+	extra, err := decodeLenient(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*r = Retention(decoded)
+	r.Extra = extra
+	return nil
+}
+
+func (r Retention) MarshalJSON() ([]byte, error) {
+	return encodeWithExtra(retention(r), r.Extra)
 }
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const cameraPayload = `{
+	"id": "cam-1",
+	"name": "Lobby",
+	"logicalId": "not a number",
+	"firmwareBuild": 4711,
+	"resolution": {"width": 1920, "height": 1080, "aspectRatio": "16:9"},
+	"streams": [{"name": "primary", "codec": "H264", "gop": 30, "resolution": {"width": 1920, "height": 1080, "scan": "progressive"}}],
+	"ptz": {"pan": true, "tilt": true, "zoom": false, "speeds": [1, 2]},
+	"digitalInputs": [{"id": "in-1", "debounceMs": 50}],
+	"digitalOutputs": [{"id": "out-1", "name": "Door", "pulse": {"ms": 500}}],
+	"analytics": {"objectClasses": ["PERSON"], "classifierVersion": "3.1"},
+	"retention": {"maxDays": 30, "storageTier": "archive"}
+}`
+
+func TestCameraKeepsUnknownNestedFields(t *testing.T) {
+	var c Camera
+	if err := json.Unmarshal([]byte(cameraPayload), &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Modeled fields are decoded at every level
+	if c.Resolution == nil || c.Resolution.Width != 1920 || len(c.Streams) != 1 || c.Streams[0].Resolution.Height != 1080 {
+		t.Fatalf("modeled fields not decoded: %+v", c)
+	}
+
+	extras := map[string]map[string]json.RawMessage{
+		"camera":                c.Extra,
+		"resolution":            c.Resolution.Extra,
+		"streams[0]":            c.Streams[0].Extra,
+		"streams[0].resolution": c.Streams[0].Resolution.Extra,
+		"ptz":                   c.PTZ.Extra,
+		"digitalInputs[0]":      c.DigitalInputs[0].Extra,
+		"digitalOutputs[0]":     c.DigitalOutputs[0].Extra,
+		"analytics":             c.Analytics.Extra,
+		"retention":             c.Retention.Extra,
+	}
+	want := map[string][]string{
+		"camera":                {"firmwareBuild", "logicalId"},
+		"resolution":            {"aspectRatio"},
+		"streams[0]":            {"gop"},
+		"streams[0].resolution": {"scan"},
+		"ptz":                   {"speeds"},
+		"digitalInputs[0]":      {"debounceMs"},
+		"digitalOutputs[0]":     {"pulse"},
+		"analytics":             {"classifierVersion"},
+		"retention":             {"storageTier"},
+	}
+	for path, keys := range want {
+		for _, key := range keys {
+			if _, ok := extras[path][key]; !ok {
+				t.Errorf("%s: unknown field %q was dropped (extra: %v)", path, key, extras[path])
+			}
+		}
+	}
+
+	// Encoding writes every field back
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, orig map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(cameraPayload), &orig); err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range orig {
+		if !reflect.DeepEqual(got[key], value) {
+			t.Errorf("%s: got %v after a round trip, want %v", key, got[key], value)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// decodeLenient decodes the JSON object data into the struct pointed to by
+// v field by field. Fields v doesn't model, and fields whose value doesn't
+// fit their Go type, are returned as received instead of failing the whole
+// object.
+func decodeLenient(data []byte, v any) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	rv := reflect.ValueOf(v).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		name := jsonName(rt.Field(i))
+		raw, ok := fields[name]
+		if name == "" || !ok {
+			continue
+		}
+		field := reflect.New(rt.Field(i).Type)
+		if err := json.Unmarshal(raw, field.Interface()); err != nil {
+			continue
+		}
+		rv.Field(i).Set(field.Elem())
+		delete(fields, name)
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// encodeWithExtra encodes v, then adds the fields of extra. Fields that v
+// also encodes take the value from extra, since extra only holds what v
+// could not decode.
+func encodeWithExtra(v any, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(extra))
+	conflict := false
+	for key := range extra {
+		keys = append(keys, key)
+		_, exists := fields[key]
+		conflict = conflict || exists
+	}
+	sort.Strings(keys)
+
+	if conflict {
+		for key, raw := range extra {
+			fields[key] = raw
+		}
+		return json.Marshal(fields)
+	}
+
+	// Append after the modeled fields to keep their order
+	var buf bytes.Buffer
+	buf.Write(bytes.TrimSuffix(bytes.TrimSpace(data), []byte("}")))
+	for i, key := range keys {
+		if i > 0 || len(fields) > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(extra[key])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonName returns the JSON key of a struct field, or "" if it is skipped.
+func jsonName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return name
+}